@@ -2,21 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/admission"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/handler"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/llm"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
-	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	_ "github.com/kdduha/itmo-megaschool-2026/backend/docs"
@@ -33,41 +36,111 @@ func main() {
 	}
 
 	logger := log.Default()
-	explainService := service.NewExplainService(
-		logger,
-		openai.NewClient(
-			option.WithAPIKey(cfg.OpenAI.APIKey),
-			option.WithBaseURL(cfg.OpenAI.BaseURL),
-		), cfg.OpenAI)
+	backends, err := llm.NewPool(logger, cfg.OpenAI)
+	if err != nil {
+		log.Fatalf("llm backends error: %v", err)
+	}
+	go backends.Run(ctx)
 
+	queue := admission.NewQueue(cfg.Queue)
+	explainService := service.NewExplainService(logger, backends, queue, cfg.OpenAI, cfg.Upload, cfg.Queue)
+
+	var (
+		closers    []func()
+		cacheAdmin *service.CacheAdminService
+	)
 	if cfg.CacheEnable {
-		redisCache := cache.NewRedisCache(
-			cfg.RedisConfig.Addr,
-			cfg.RedisConfig.Password,
-			cfg.RedisConfig.DB,
-			cfg.RedisConfig.TTL,
-		)
-		explainService.SetCacheClient(redisCache)
-		logger.Println("set redis as cache")
+		explainCache, closeCache, err := newExplainCache(logger, cfg)
+		if err != nil {
+			log.Fatalf("cache error: %v", err)
+		}
+		closers = append(closers, closeCache)
+		explainService.SetCacheClient(explainCache, cfg.CacheNamespace, cfg.CacheVersion, cfg.CacheReplayDelay)
+		explainService.SetPreprocessCache(cfg.CachePreprocessMaxBytes)
+		logger.Printf("set %s as cache\n", cfg.CacheBackend)
+
+		if cfg.SemanticCache.Enable {
+			baseURL := cfg.SemanticCache.BaseURL
+			if baseURL == "" {
+				baseURL = cfg.OpenAI.BaseURL
+			}
+			embedder := llm.NewEmbedder(baseURL, cfg.OpenAI.APIKey, cfg.SemanticCache.Model, cfg.SemanticCache.Timeout)
+			explainService.SetSemanticCache(embedder, cfg.SemanticCache.Threshold, cfg.SemanticCache.MaxPrompts)
+			logger.Printf("semantic cache enabled with %s at %s\n", cfg.SemanticCache.Model, baseURL)
+		}
+
+		if cfg.AdminToken != "" {
+			cacheAdmin = service.NewCacheAdminService(logger, explainCache, explainService)
+		} else {
+			logger.Println("cache admin API is disabled, ADMIN_TOKEN is not set")
+		}
+	}
+
+	jobStore, closeJobs, err := newStore(logger, cfg, "jobs", cfg.Jobs.TTL, false)
+	if err != nil {
+		log.Fatalf("job store error: %v", err)
+	}
+	closers = append(closers, closeJobs)
+	jobService := service.NewJobService(logger, explainService, jobStore, cfg.CacheNamespace, cfg.Jobs)
+
+	batchService := service.NewBatchService(logger, explainService, cfg.Batch)
+
+	sessionStore, closeSessions, err := newStore(logger, cfg, "sessions", cfg.Sessions.TTL, false)
+	if err != nil {
+		log.Fatalf("session store error: %v", err)
 	}
+	closers = append(closers, closeSessions)
+	sessionService := service.NewSessionService(logger, explainService, sessionStore, cfg.CacheNamespace, cfg.Sessions)
+
+	streamHub := service.NewStreamHub(cfg.Stream)
 
-	e := handler.NewExplainHandler(explainService)
+	e := handler.NewExplainHandler(explainService, streamHub, cfg.Upload)
+	j := handler.NewJobHandler(jobService, cfg.Upload)
+	b := handler.NewBatchHandler(batchService, cfg.Upload)
+	c := handler.NewConvertHandler(explainService, cfg.Upload)
+	s := handler.NewSessionHandler(sessionService, streamHub, cfg.Upload)
 
 	r := chi.NewRouter()
-	r.Use([]func(http.Handler) http.Handler{
-		middleware.Logger,
-		middleware.Recoverer,
-		middleware.Throttle(cfg.Server.ThrottleLimit),
-		middleware.Timeout(cfg.Server.Timeout),
-		metrics.Middleware,
-	}...)
-
-	r.Post("/explain", e.Explain)
-	r.Post("/explain/stream", e.ExplainStream)
-	r.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"),
-	))
-	r.Handle("/metrics", promhttp.Handler())
+	r.Use(middleware.Logger, middleware.Recoverer)
+
+	// batches run many model calls, they are limited by BATCH_TIMEOUT instead of SERVER_TIMEOUT
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(cfg.Batch.Timeout), metrics.Middleware)
+		r.Post("/explain/batch", b.Explain)
+		r.Post("/explain/batch/stream", b.ExplainStream)
+	})
+
+	// SSE connections last as long as the generation, they are limited by STREAM_MAX_DURATION
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(cfg.Stream.MaxDuration), metrics.Middleware)
+		r.Post("/explain/stream", e.ExplainStream)
+		r.Post("/sessions/{id}/messages/stream", s.MessageStream)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(cfg.Server.Timeout), metrics.Middleware)
+		r.Post("/explain", e.Explain)
+		r.Post("/convert", c.Convert)
+		r.Post("/jobs", j.Create)
+		r.Get("/jobs/{id}", j.Get)
+		r.Delete("/jobs/{id}", j.Cancel)
+		r.Post("/sessions", s.Create)
+		r.Post("/sessions/{id}/messages", s.Message)
+		if cacheAdmin != nil {
+			a := handler.NewCacheAdminHandler(cacheAdmin)
+			r.Route("/admin/cache", func(r chi.Router) {
+				r.Use(handler.RequireToken(cfg.AdminToken))
+				r.Get("/stats", a.Stats)
+				r.Get("/entries/{hash}", a.Lookup)
+				r.Delete("/entries/{hash}", a.Delete)
+				r.Post("/purge", a.Purge)
+			})
+		}
+		r.Get("/swagger/*", httpSwagger.Handler(
+			httpSwagger.URL("/swagger/doc.json"),
+		))
+		r.Handle("/metrics", promhttp.Handler())
+	})
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
@@ -89,5 +162,72 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Fatalf("server forced to shutdown: %v", err)
 	}
+	jobService.Close()
+	for _, closeStore := range closers {
+		closeStore()
+	}
 	logger.Println("server stopped")
 }
+
+// newExplainCache puts the in-memory LRU in front of redis, disk and memory backends are used as is
+func newExplainCache(logger *log.Logger, cfg *config.Config) (service.AdminCache, func(), error) {
+	switch cfg.CacheBackend {
+	case config.CacheBackendRedis:
+		redisCache := cache.NewRedisCache(
+			cfg.RedisConfig.Addr,
+			cfg.RedisConfig.Password,
+			cfg.RedisConfig.DB,
+			cfg.RedisConfig.TTL,
+		)
+		tieredCache := cache.NewTieredCache(
+			logger,
+			cache.NewMemoryCache(cfg.CacheTiers.MemoryMaxBytes, cfg.CacheTiers.MemoryTTL),
+			redisCache,
+			cfg.CacheTiers.RedisRetryInterval,
+		)
+		return tieredCache, func() {}, nil
+	case config.CacheBackendDisk:
+		return newStore(logger, cfg, "explain", cfg.CacheDisk.TTL, true)
+	default:
+		return newStore(logger, cfg, "explain", cfg.CacheTiers.MemoryTTL, true)
+	}
+}
+
+// newStore opens a store of the configured backend, name separates directories of the disk backend.
+// Disk and memory stores evict least recently used entries when evict is set, otherwise
+// entries are kept until they expire and saving fails when the store is full
+func newStore(logger *log.Logger, cfg *config.Config, name string, ttl time.Duration, evict bool) (service.AdminCache, func(), error) {
+	switch cfg.CacheBackend {
+	case config.CacheBackendRedis:
+		redisCache := cache.NewRedisCache(
+			cfg.RedisConfig.Addr,
+			cfg.RedisConfig.Password,
+			cfg.RedisConfig.DB,
+			ttl,
+		)
+		return redisCache, func() {}, nil
+	case config.CacheBackendDisk:
+		open := cache.NewDiskStore
+		if evict {
+			open = cache.NewDiskCache
+		}
+		diskCache, err := open(
+			logger,
+			filepath.Join(cfg.CacheDisk.Dir, name),
+			cfg.CacheDisk.MaxBytes,
+			ttl,
+			cfg.CacheDisk.SweepInterval,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return diskCache, diskCache.Close, nil
+	case config.CacheBackendMemory:
+		if evict {
+			return cache.NewMemoryCache(cfg.CacheTiers.MemoryMaxBytes, ttl), func() {}, nil
+		}
+		return cache.NewMemoryStore(cfg.CacheTiers.MemoryMaxBytes, ttl), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown cache backend %q", cfg.CacheBackend)
+	}
+}
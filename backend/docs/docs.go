@@ -15,9 +15,136 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/explain": {
+        "/admin/cache/entries/{hash}": {
+            "get": {
+                "description": "Get cache entries of every kind of the current cache version by file content hash (sha256 of the decoded file) or by <file hash>:<request hash>",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Look up cache entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File hash or <file hash>:<request hash>",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CacheEntry"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete cache entries of every kind of the current cache version by file content hash or by <file hash>:<request hash>.\nWith the redis backend the memory tier of other replicas keeps its copies until CACHE_MEMORY_TTL",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete cache entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File hash or <file hash>:<request hash>",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CachePurgeResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache/purge": {
             "post": {
-                "description": "Explain architecture from image + prompt. Image is sent as base64 string in JSON.",
+                "description": "Delete cache entries by key prefix, kind, model, prompt version or cache version. All given filters must match.\nKeys are listed by a scan per kind, kind, cache version and prefix filters narrow the scan\nWith the redis backend the memory tier of other replicas keeps its copies until CACHE_MEMORY_TTL",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,17 +152,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "explain"
+                    "admin"
                 ],
-                "summary": "Explain diagram image",
+                "summary": "Purge cache entries",
                 "parameters": [
                     {
-                        "description": "Explain request",
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Purge filters",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ExplainRequest"
+                            "$ref": "#/definitions/models.CachePurgeRequest"
                         }
                     }
                 ],
@@ -43,7 +177,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ExplainResponse"
+                            "$ref": "#/definitions/models.CachePurgeResponse"
                         }
                     },
                     "400": {
@@ -55,6 +189,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -67,19 +210,156 @@ const docTemplate = `{
                 }
             }
         },
-        "/explain/stream": {
+        "/admin/cache/stats": {
+            "get": {
+                "description": "Entries and bytes of cache entries of every kind per cache tier, answer hits and misses since the backend start",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Cache statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CacheStats"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/convert": {
             "post": {
-                "description": "Stream explanation tokens from image + prompt. Image is sent as base64 string in JSON.",
+                "description": "Convert diagram to Mermaid or PlantUML source. Generated code is validated and the model is asked to fix syntax errors. File is sent as base64 string in JSON or as a raw \"file\" part of multipart/form-data.",
                 "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "convert"
+                ],
+                "summary": "Convert diagram",
+                "parameters": [
+                    {
+                        "description": "Convert request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ConvertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConvertResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/explain": {
+            "post": {
+                "description": "Explain architecture from image + prompt. Image is sent as base64 string in JSON or as a raw \"file\" part of multipart/form-data.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
                 "produces": [
-                    "text/event-stream"
+                    "application/json"
                 ],
                 "tags": [
                     "explain"
                 ],
-                "summary": "Stream explanation",
+                "summary": "Explain diagram image",
                 "parameters": [
                     {
                         "description": "Explain request",
@@ -93,9 +373,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Stream of tokens (SSE)",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.StreamChunk"
+                            "$ref": "#/definitions/models.ExplainResponse"
                         }
                     },
                     "400": {
@@ -107,6 +387,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -115,22 +404,945 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.ExplainRequest": {
-            "type": "object",
-            "properties": {
-                "file_base64": {
-                    "type": "string",
-                    "example": "iVBORw0KGgoAAAANSUhEUgAA..."
-                },
-                "file_format": {
-                    "type": "string",
-                    "example": "png"
+        },
+        "/explain/batch": {
+            "post": {
+                "description": "Explain a batch of images + prompts. Items are processed concurrently and results are returned in request order with per-item errors.\nItems left when the batch runs into BATCH_TIMEOUT are returned with an error next to the finished ones.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "explain"
+                ],
+                "summary": "Explain many diagrams",
+                "parameters": [
+                    {
+                        "description": "Batch explain request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchExplainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchExplainResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/explain/batch/stream": {
+            "post": {
+                "description": "Explain a batch of images + prompts and emit each item as NDJSON line as soon as it finishes.\nItems left when the batch is cancelled or runs into BATCH_TIMEOUT are emitted with an error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "explain"
+                ],
+                "summary": "Stream batch explanation",
+                "parameters": [
+                    {
+                        "description": "Batch explain request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchExplainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of items (NDJSON)",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchItemResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/explain/stream": {
+            "post": {
+                "description": "Stream explanation tokens from image + prompt. Image is sent as base64 string in JSON or as a raw \"file\" part of multipart/form-data. Besides message events the stream has queued, meta, preprocessing, preprocessed, generating and usage events and heartbeat comments. Queued events with a position are sent while the model call waits in the queue. Events have ids, a client reconnecting with Last-Event-ID header gets the missed events and the rest of the stream, the body is ignored then.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "explain"
+                ],
+                "summary": "Stream explanation",
+                "parameters": [
+                    {
+                        "description": "Explain request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExplainRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last received event id to resume the stream",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of tokens (SSE)",
+                        "schema": {
+                            "$ref": "#/definitions/models.StreamChunk"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "post": {
+                "description": "Queue explanation of image + prompt and return job ID immediately. Image is sent as base64 string in JSON or as a raw \"file\" part of multipart/form-data.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Create explanation job",
+                "parameters": [
+                    {
+                        "description": "Explain request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExplainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.JobCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Get job status, progress, result and error",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get explanation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancel queued or running job. Finished jobs are returned unchanged",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Cancel explanation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "post": {
+                "description": "Upload and preprocess a diagram once to ask follow-up questions about it. File is sent as base64 string in JSON or as a raw \"file\" part of multipart/form-data. Optional prompt is kept as diagram context.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Create conversation session",
+                "parameters": [
+                    {
+                        "description": "Diagram",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExplainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}/messages": {
+            "post": {
+                "description": "Answer a question about the session diagram taking previous messages into account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Ask about session diagram",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExplainResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}/messages/stream": {
+            "post": {
+                "description": "Stream answer tokens for a question about the session diagram, events and resumption with Last-Event-ID are the same as for /explain/stream",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Stream answer about session diagram",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionMessageRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last received event id to resume the stream",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of tokens (SSE)",
+                        "schema": {
+                            "$ref": "#/definitions/models.StreamChunk"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.BatchExplainRequest": {
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "description": "Optional number of items processed in parallel, capped by server config",
+                    "type": "integer",
+                    "example": 2
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExplainRequest"
+                    }
+                }
+            }
+        },
+        "models.BatchExplainResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchItemResult"
+                    }
+                }
+            }
+        },
+        "models.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "diagram.png"
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "result": {
+                    "$ref": "#/definitions/models.ExplainResponse"
+                }
+            }
+        },
+        "models.CacheEntry": {
+            "type": "object",
+            "properties": {
+                "cache_version": {
+                    "type": "string",
+                    "example": "v1"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "explanation": {
+                    "type": "string"
+                },
+                "file_hash": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "diagram-ai:explain:v1:9f86d081884c7d65:2c26b46b68ffc68f"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "explain"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "qwen2.5-vl-7b"
+                },
+                "pages": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "prompt_version": {
+                    "type": "string",
+                    "example": "1"
+                },
+                "prompts": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "request_hash": {
+                    "type": "string",
+                    "example": "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 2048
+                },
+                "structured": {
+                    "$ref": "#/definitions/models.StructuredExplanation"
+                }
+            }
+        },
+        "models.CachePurgeRequest": {
+            "type": "object",
+            "properties": {
+                "cache_version": {
+                    "type": "string",
+                    "example": "v1"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "explain"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "qwen2.5-vl-7b"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "v1:"
+                },
+                "prompt_version": {
+                    "type": "string",
+                    "example": "1"
+                }
+            }
+        },
+        "models.CachePurgeResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "models.CacheStats": {
+            "type": "object",
+            "properties": {
+                "hit_ratio": {
+                    "type": "number",
+                    "example": 0.3
+                },
+                "hits": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "misses": {
+                    "type": "integer",
+                    "example": 70
+                },
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CacheTierStats"
+                    }
+                }
+            }
+        },
+        "models.CacheTierStats": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer",
+                    "example": 524288
+                },
+                "entries": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "tier": {
+                    "type": "string",
+                    "example": "redis"
+                }
+            }
+        },
+        "models.Component": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Handles business logic"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Backend API"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "service"
+                }
+            }
+        },
+        "models.ConvertRequest": {
+            "type": "object",
+            "required": [
+                "file_base64",
+                "file_name"
+            ],
+            "properties": {
+                "file_base64": {
+                    "type": "string",
+                    "example": "iVBORw0KGgoAAAANSUhEUgAA..."
+                },
+                "file_format": {
+                    "type": "string",
+                    "example": "png"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "diagram.png"
+                },
+                "generation": {
+                    "description": "Optional generation parameters",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.GenerationParams"
+                        }
+                    ]
+                },
+                "output": {
+                    "description": "Optional output mode, \"structured\" returns a typed explanation",
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "structured"
+                    ],
+                    "example": "text"
+                },
+                "prompt": {
+                    "type": "string",
+                    "example": "Explain architecture"
+                },
+                "target": {
+                    "description": "Target diagram language",
+                    "type": "string",
+                    "enum": [
+                        "mermaid",
+                        "plantuml"
+                    ],
+                    "example": "mermaid"
+                }
+            }
+        },
+        "models.ConvertResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Number of model calls, answers failing validation are sent back with the syntax error.\nZero when the uploaded text already was valid target code",
+                    "type": "integer",
+                    "example": 1
+                },
+                "declared_format": {
+                    "type": "string",
+                    "example": "jpg"
+                },
+                "detected_format": {
+                    "type": "string",
+                    "example": "png"
+                },
+                "format_warning": {
+                    "type": "string"
+                },
+                "model": {
+                    "description": "Model that wrote the code, empty when no model was called",
+                    "type": "string",
+                    "example": "qwen2.5-7b"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "flowchart LR\n  A[Client] --\u003e B[API]"
+                },
+                "target": {
+                    "type": "string",
+                    "example": "mermaid"
+                }
+            }
+        },
+        "models.ExplainRequest": {
+            "type": "object",
+            "required": [
+                "file_base64",
+                "file_name"
+            ],
+            "properties": {
+                "file_base64": {
+                    "type": "string",
+                    "example": "iVBORw0KGgoAAAANSUhEUgAA..."
+                },
+                "file_format": {
+                    "type": "string",
+                    "example": "png"
                 },
                 "file_name": {
                     "type": "string",
@@ -144,6 +1356,15 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "output": {
+                    "description": "Optional output mode, \"structured\" returns a typed explanation",
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "structured"
+                    ],
+                    "example": "text"
+                },
                 "prompt": {
                     "type": "string",
                     "example": "Explain architecture"
@@ -153,8 +1374,41 @@ const docTemplate = `{
         "models.ExplainResponse": {
             "type": "object",
             "properties": {
+                "cached": {
+                    "description": "Model, Usage and Latency of the model call, absent for cached answers.\nSimilarity is set when the answer was cached for a similar prompt",
+                    "type": "boolean",
+                    "example": false
+                },
+                "declared_format": {
+                    "type": "string",
+                    "example": "jpg"
+                },
+                "detected_format": {
+                    "type": "string",
+                    "example": "png"
+                },
                 "explanation": {
                     "type": "string"
+                },
+                "format_warning": {
+                    "type": "string"
+                },
+                "latency": {
+                    "$ref": "#/definitions/models.Latency"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "minicpm-v"
+                },
+                "similarity": {
+                    "type": "number",
+                    "example": 0.95
+                },
+                "structured": {
+                    "$ref": "#/definitions/models.StructuredExplanation"
+                },
+                "usage": {
+                    "$ref": "#/definitions/models.Usage"
                 }
             }
         },
@@ -173,14 +1427,217 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f1c9a7e5b2d4c6a8e0f1a2b3c4d5e6f"
+                },
+                "progress": {
+                    "type": "number",
+                    "example": 0.4
+                },
+                "result": {
+                    "$ref": "#/definitions/models.ExplainResponse"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.JobStatus"
+                        }
+                    ],
+                    "example": "running"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobCreateResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "3f1c9a7e5b2d4c6a8e0f1a2b3c4d5e6f"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.JobStatus"
+                        }
+                    ],
+                    "example": "queued"
+                }
+            }
+        },
+        "models.JobStatus": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "done",
+                "failed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "JobQueued",
+                "JobRunning",
+                "JobDone",
+                "JobFailed",
+                "JobCancelled"
+            ]
+        },
+        "models.Latency": {
+            "type": "object",
+            "properties": {
+                "time_to_first_token_ms": {
+                    "type": "integer",
+                    "example": 850
+                },
+                "tokens_per_second": {
+                    "type": "number",
+                    "example": 56.3
+                },
+                "total_ms": {
+                    "type": "integer",
+                    "example": 5400
+                }
+            }
+        },
+        "models.Relationship": {
+            "type": "object",
+            "properties": {
+                "direction": {
+                    "type": "string",
+                    "enum": [
+                        "forward",
+                        "backward",
+                        "bidirectional",
+                        "none"
+                    ],
+                    "example": "forward"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "HTTPS / JSON"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "Web Frontend"
+                },
+                "target": {
+                    "type": "string",
+                    "example": "Backend API"
+                }
+            }
+        },
+        "models.SessionCreateResponse": {
+            "type": "object",
+            "properties": {
+                "declared_format": {
+                    "type": "string",
+                    "example": "jpg"
+                },
+                "detected_format": {
+                    "type": "string",
+                    "example": "png"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "format_warning": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f1c9a7e5b2d4c6a8e0f1a2b3c4d5e6f"
+                }
+            }
+        },
+        "models.SessionMessageRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "What happens if PageService fails?"
+                },
+                "generation": {
+                    "description": "Optional generation parameters",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.GenerationParams"
+                        }
+                    ]
+                }
+            }
+        },
         "models.StreamChunk": {
             "type": "object",
             "properties": {
                 "delta": {
                     "type": "string"
                 },
-                "explanation": {
-                    "type": "string"
+                "structured": {
+                    "$ref": "#/definitions/models.StructuredExplanation"
+                }
+            }
+        },
+        "models.StructuredExplanation": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Component"
+                    }
+                },
+                "diagram_type": {
+                    "type": "string",
+                    "example": "C4 container"
+                },
+                "issues": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "relationships": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Relationship"
+                    }
+                },
+                "summary": {
+                    "type": "string",
+                    "example": "Web app calls backend API which stores orders in PostgreSQL"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Online orders system"
+                }
+            }
+        },
+        "models.Usage": {
+            "type": "object",
+            "properties": {
+                "completion_tokens": {
+                    "type": "integer",
+                    "example": 256
+                },
+                "prompt_tokens": {
+                    "type": "integer",
+                    "example": 812
+                },
+                "total_tokens": {
+                    "type": "integer",
+                    "example": 1068
                 }
             }
         }
@@ -0,0 +1,205 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+)
+
+var (
+	ErrQueueFull    = errors.New("model queue is full")
+	ErrQueueTimeout = errors.New("model queue timeout")
+)
+
+// Priority is a request class, lower values are admitted first
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota
+	PriorityBatch
+
+	priorities = 2
+)
+
+func (p Priority) String() string {
+	if p == PriorityBatch {
+		return "batch"
+	}
+	return "interactive"
+}
+
+type priorityKey struct{}
+
+// WithPriority marks model calls made with ctx, calls are interactive by default
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFrom returns the priority ctx is marked with
+func PriorityFrom(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+type ticket struct {
+	priority Priority
+	ready    chan struct{}
+
+	// latest queue position, older values are dropped
+	position chan int
+	reported int
+}
+
+// Queue admits at most concurrency model calls, the rest wait in a bounded queue by priority
+type Queue struct {
+	concurrency int
+	maxSize     int
+	timeout     time.Duration
+
+	mu      sync.Mutex
+	running int
+	waiting [priorities][]*ticket
+}
+
+func NewQueue(cfg config.QueueConfig) *Queue {
+	return &Queue{
+		concurrency: max(cfg.Concurrency, 1),
+		maxSize:     cfg.MaxSize,
+		timeout:     cfg.Timeout,
+	}
+}
+
+// Acquire waits for a free slot for at most the queue timeout, batch calls wait until ctx is done. onPosition, if set, gets the
+// 1-based queue position each time it changes. release must be called after the model call
+func (q *Queue) Acquire(ctx context.Context, onPosition func(position int)) (release func(), err error) {
+	priority := PriorityFrom(ctx)
+	start := time.Now()
+
+	q.mu.Lock()
+	if q.running < q.concurrency && q.size() == 0 {
+		q.running++
+		metrics.QueueRunning(q.running)
+		q.mu.Unlock()
+
+		metrics.QueueWait(priority.String(), "admitted", 0)
+		return q.releaseFunc(), nil
+	}
+	if q.size() >= q.maxSize {
+		q.mu.Unlock()
+		metrics.QueueWait(priority.String(), "rejected", 0)
+		return nil, ErrQueueFull
+	}
+
+	t := &ticket{
+		priority: priority,
+		ready:    make(chan struct{}),
+		position: make(chan int, 1),
+	}
+	q.waiting[priority] = append(q.waiting[priority], t)
+	q.updatePositions()
+	q.mu.Unlock()
+
+	// batch calls wait as long as their job or batch runs
+	var timeout <-chan time.Time
+	if priority != PriorityBatch {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-t.ready:
+			metrics.QueueWait(priority.String(), "admitted", time.Since(start))
+			return q.releaseFunc(), nil
+		case position := <-t.position:
+			if onPosition != nil {
+				onPosition(position)
+			}
+		case <-ctx.Done():
+			q.leave(t)
+			metrics.QueueWait(priority.String(), "cancelled", time.Since(start))
+			return nil, ctx.Err()
+		case <-timeout:
+			q.leave(t)
+			metrics.QueueWait(priority.String(), "timeout", time.Since(start))
+			return nil, ErrQueueTimeout
+		}
+	}
+}
+
+// leave removes a waiting ticket, the slot is passed on if it was granted meanwhile
+func (q *Queue) leave(t *ticket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := slices.Index(q.waiting[t.priority], t)
+	if i < 0 {
+		q.release()
+		return
+	}
+	q.waiting[t.priority] = slices.Delete(q.waiting[t.priority], i, i+1)
+	q.updatePositions()
+}
+
+func (q *Queue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.release()
+		})
+	}
+}
+
+// release passes the slot to the first waiting ticket of the highest priority
+func (q *Queue) release() {
+	for priority := range q.waiting {
+		if len(q.waiting[priority]) == 0 {
+			continue
+		}
+		t := q.waiting[priority][0]
+		q.waiting[priority] = q.waiting[priority][1:]
+		close(t.ready)
+		q.updatePositions()
+		return
+	}
+
+	q.running--
+	metrics.QueueRunning(q.running)
+}
+
+func (q *Queue) size() int {
+	var size int
+	for _, tickets := range q.waiting {
+		size += len(tickets)
+	}
+	return size
+}
+
+func (q *Queue) updatePositions() {
+	position := 0
+	for priority, tickets := range q.waiting {
+		metrics.QueueDepth(Priority(priority).String(), len(tickets))
+		for _, t := range tickets {
+			position++
+			if t.reported == position {
+				continue
+			}
+			t.reported = position
+			select {
+			case <-t.position:
+			default:
+			}
+			t.position <- position
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+)
+
+func TestQueueTimeoutSkipsBatch(t *testing.T) {
+	q := NewQueue(config.QueueConfig{Concurrency: 1, MaxSize: 10, Timeout: 20 * time.Millisecond})
+
+	release, err := q.Acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+
+	if _, err := q.Acquire(context.Background(), nil); !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("interactive call error = %v, want ErrQueueTimeout", err)
+	}
+
+	admitted := make(chan error, 1)
+	go func() {
+		release, err := q.Acquire(WithPriority(context.Background(), PriorityBatch), nil)
+		if err == nil {
+			release()
+		}
+		admitted <- err
+	}()
+
+	select {
+	case err := <-admitted:
+		t.Fatalf("batch call returned before a slot was free: %v", err)
+	case <-time.After(5 * q.timeout):
+	}
+
+	release()
+	select {
+	case err := <-admitted:
+		if err != nil {
+			t.Fatalf("batch call error = %v, want admitted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch call wasn't admitted after the slot was released")
+	}
+}
+
+func TestQueueBatchWaitEndsWithContext(t *testing.T) {
+	q := NewQueue(config.QueueConfig{Concurrency: 1, MaxSize: 10, Timeout: time.Hour})
+
+	release, err := q.Acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(WithPriority(context.Background(), PriorityBatch), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Acquire(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("batch call error = %v, want context.DeadlineExceeded", err)
+	}
+	if size := q.size(); size != 0 {
+		t.Fatalf("queue size = %d after the batch call left, want 0", size)
+	}
+}
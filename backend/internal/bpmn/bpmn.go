@@ -0,0 +1,246 @@
+package bpmn
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Definitions is a parsed BPMN 2.0 document
+type Definitions struct {
+	Participants []Participant
+	Processes    []Process
+	MessageFlows []Flow
+}
+
+// Participant is a pool of a collaboration diagram
+type Participant struct {
+	ID         string
+	Name       string
+	ProcessRef string
+}
+
+// Process holds flow elements of a process or of a subprocess
+type Process struct {
+	ID       string
+	Name     string
+	Lanes    []Lane
+	Elements []Element
+	Flows    []Flow
+}
+
+// Lane groups flow elements of a process by performer
+type Lane struct {
+	ID       string
+	Name     string
+	Elements []string
+}
+
+// Element is a flow node: task, gateway, event, subprocess or data reference
+type Element struct {
+	ID         string
+	Name       string
+	Kind       string
+	Category   string
+	Definition string
+	AttachedTo string
+	Default    string
+	Documents  []string
+	Subprocess *Process
+}
+
+// Flow is a sequence flow inside a process or a message flow between pools
+type Flow struct {
+	ID        string
+	Name      string
+	Source    string
+	Target    string
+	Condition string
+}
+
+const (
+	CategoryTask       = "task"
+	CategoryGateway    = "gateway"
+	CategoryEvent      = "event"
+	CategorySubprocess = "subprocess"
+	CategoryData       = "data"
+)
+
+var elementKinds = map[string]string{
+	"task":                   CategoryTask,
+	"userTask":               CategoryTask,
+	"serviceTask":            CategoryTask,
+	"scriptTask":             CategoryTask,
+	"sendTask":               CategoryTask,
+	"receiveTask":            CategoryTask,
+	"manualTask":             CategoryTask,
+	"businessRuleTask":       CategoryTask,
+	"callActivity":           CategoryTask,
+	"exclusiveGateway":       CategoryGateway,
+	"parallelGateway":        CategoryGateway,
+	"inclusiveGateway":       CategoryGateway,
+	"eventBasedGateway":      CategoryGateway,
+	"complexGateway":         CategoryGateway,
+	"startEvent":             CategoryEvent,
+	"endEvent":               CategoryEvent,
+	"intermediateCatchEvent": CategoryEvent,
+	"intermediateThrowEvent": CategoryEvent,
+	"boundaryEvent":          CategoryEvent,
+	"subProcess":             CategorySubprocess,
+	"transaction":            CategorySubprocess,
+	"adHocSubProcess":        CategorySubprocess,
+	"dataObjectReference":    CategoryData,
+	"dataStoreReference":     CategoryData,
+}
+
+// element is a namespace agnostic XML tree, BPMN tools use different prefixes
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []element  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+func (e element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (e element) children(name string) []element {
+	var result []element
+	for _, c := range e.Children {
+		if c.XMLName.Local == name {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Parse reads BPMN 2.0 XML. Diagram interchange (layout) data is ignored
+func Parse(data []byte) (*Definitions, error) {
+	var root element
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid bpmn xml: %w", err)
+	}
+	if root.XMLName.Local != "definitions" {
+		return nil, fmt.Errorf("unexpected root element <%s>", root.XMLName.Local)
+	}
+
+	defs := &Definitions{}
+	for _, c := range root.Children {
+		switch c.XMLName.Local {
+		case "collaboration":
+			for _, p := range c.children("participant") {
+				defs.Participants = append(defs.Participants, Participant{
+					ID:         p.attr("id"),
+					Name:       cleanName(p.attr("name")),
+					ProcessRef: p.attr("processRef"),
+				})
+			}
+			for _, f := range c.children("messageFlow") {
+				defs.MessageFlows = append(defs.MessageFlows, parseFlow(f))
+			}
+		case "process":
+			defs.Processes = append(defs.Processes, *parseProcess(c))
+		}
+	}
+
+	if len(defs.Processes) == 0 && len(defs.Participants) == 0 {
+		return nil, fmt.Errorf("bpmn has no processes")
+	}
+	return defs, nil
+}
+
+func parseProcess(e element) *Process {
+	process := &Process{
+		ID:   e.attr("id"),
+		Name: cleanName(e.attr("name")),
+	}
+
+	for _, c := range e.Children {
+		name := c.XMLName.Local
+		switch {
+		case name == "laneSet":
+			process.Lanes = append(process.Lanes, parseLanes(c)...)
+		case name == "sequenceFlow":
+			process.Flows = append(process.Flows, parseFlow(c))
+		case elementKinds[name] != "":
+			process.Elements = append(process.Elements, parseElement(c))
+		}
+	}
+	return process
+}
+
+func parseLanes(laneSet element) []Lane {
+	var lanes []Lane
+	for _, l := range laneSet.children("lane") {
+		lane := Lane{
+			ID:   l.attr("id"),
+			Name: cleanName(l.attr("name")),
+		}
+		for _, ref := range l.children("flowNodeRef") {
+			lane.Elements = append(lane.Elements, strings.TrimSpace(ref.Text))
+		}
+		lanes = append(lanes, lane)
+
+		// nested lanes are flattened, they still reference the same flow nodes
+		for _, child := range l.children("childLaneSet") {
+			lanes = append(lanes, parseLanes(child)...)
+		}
+	}
+	return lanes
+}
+
+func parseElement(e element) Element {
+	kind := e.XMLName.Local
+	el := Element{
+		ID:         e.attr("id"),
+		Name:       cleanName(e.attr("name")),
+		Kind:       kind,
+		Category:   elementKinds[kind],
+		AttachedTo: e.attr("attachedToRef"),
+		Default:    e.attr("default"),
+	}
+
+	for _, c := range e.Children {
+		if def, ok := strings.CutSuffix(c.XMLName.Local, "EventDefinition"); ok {
+			el.Definition = def
+		}
+		if c.XMLName.Local == "dataOutputAssociation" || c.XMLName.Local == "dataInputAssociation" {
+			for _, ref := range append(c.children("targetRef"), c.children("sourceRef")...) {
+				if id := strings.TrimSpace(ref.Text); id != "" && id != el.ID {
+					el.Documents = append(el.Documents, id)
+				}
+			}
+		}
+	}
+
+	if el.Category == CategorySubprocess {
+		el.Subprocess = parseProcess(e)
+	}
+	return el
+}
+
+func parseFlow(e element) Flow {
+	flow := Flow{
+		ID:     e.attr("id"),
+		Name:   cleanName(e.attr("name")),
+		Source: e.attr("sourceRef"),
+		Target: e.attr("targetRef"),
+	}
+	for _, c := range e.children("conditionExpression") {
+		flow.Condition = strings.TrimSpace(c.Text)
+		if flow.Condition == "" {
+			flow.Condition = "conditional"
+		}
+	}
+	return flow
+}
+
+func cleanName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
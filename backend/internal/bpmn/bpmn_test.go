@@ -0,0 +1,215 @@
+package bpmn
+
+import (
+	"testing"
+)
+
+func definitions(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" id="Definitions_1">` + body + `</bpmn:definitions>`
+}
+
+func TestDescribe(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		want string
+	}{
+		"pools and lanes": {
+			data: definitions(`
+<bpmn:collaboration id="Collab">
+  <bpmn:participant id="P_shop" name="Shop" processRef="Shop_process"/>
+  <bpmn:participant id="P_bank" name="Bank"/>
+</bpmn:collaboration>
+<bpmn:process id="Shop_process">
+  <bpmn:laneSet>
+    <bpmn:lane id="L_sales" name="Sales">
+      <bpmn:flowNodeRef>Start</bpmn:flowNodeRef>
+      <bpmn:flowNodeRef>Take</bpmn:flowNodeRef>
+      <bpmn:childLaneSet>
+        <bpmn:lane id="L_desk" name="Front   desk">
+          <bpmn:flowNodeRef>Take</bpmn:flowNodeRef>
+        </bpmn:lane>
+      </bpmn:childLaneSet>
+    </bpmn:lane>
+    <bpmn:lane id="L_store" name="Warehouse">
+      <bpmn:flowNodeRef>Ship</bpmn:flowNodeRef>
+    </bpmn:lane>
+  </bpmn:laneSet>
+  <bpmn:userTask id="Take" name="Take order"/>
+  <bpmn:startEvent id="Start" name="Order"><bpmn:messageEventDefinition/></bpmn:startEvent>
+  <bpmn:serviceTask id="Ship" name="Ship goods"/>
+  <bpmn:sequenceFlow id="F2" sourceRef="Take" targetRef="Ship"/>
+  <bpmn:sequenceFlow id="F1" sourceRef="Start" targetRef="Take"/>
+</bpmn:process>`),
+			want: `Pool "Shop"
+Lanes:
+- "Sales": message start event "Order", user task "Take order"
+- "Front desk": user task "Take order"
+- "Warehouse": service task "Ship goods"
+Elements in flow order:
+1. message start event "Order"
+2. user task "Take order"
+3. service task "Ship goods"
+Sequence flows:
+- message start event "Order" -> user task "Take order"
+- user task "Take order" -> service task "Ship goods"
+
+Pool "Bank"
+(black box pool)`,
+		},
+		"subprocess": {
+			data: definitions(`
+<bpmn:process id="Main" name="Claims">
+  <bpmn:startEvent id="S"/>
+  <bpmn:subProcess id="Check" name="Check claim">
+    <bpmn:startEvent id="S2"/>
+    <bpmn:task id="Verify" name="Verify documents"/>
+    <bpmn:endEvent id="E2"/>
+    <bpmn:sequenceFlow id="SF1" sourceRef="S2" targetRef="Verify"/>
+    <bpmn:sequenceFlow id="SF2" sourceRef="Verify" targetRef="E2"/>
+  </bpmn:subProcess>
+  <bpmn:boundaryEvent id="Timeout" name="2 days" attachedToRef="Check"><bpmn:timerEventDefinition/></bpmn:boundaryEvent>
+  <bpmn:endEvent id="E"/>
+  <bpmn:sequenceFlow id="F1" sourceRef="S" targetRef="Check"/>
+  <bpmn:sequenceFlow id="F2" sourceRef="Check" targetRef="E"/>
+</bpmn:process>`),
+			want: `Process "Claims"
+Elements in flow order:
+1. start event (S)
+2. subprocess "Check claim"
+3. timer boundary event "2 days" attached to subprocess "Check claim"
+4. end event (E)
+Sequence flows:
+- start event (S) -> subprocess "Check claim"
+- subprocess "Check claim" -> end event (E)
+Inside subprocess "Check claim":
+  Elements in flow order:
+  1. start event (S2)
+  2. task "Verify documents"
+  3. end event (E2)
+  Sequence flows:
+  - start event (S2) -> task "Verify documents"
+  - task "Verify documents" -> end event (E2)`,
+		},
+		"gateway with labeled flows": {
+			data: definitions(`
+<bpmn:process id="Approval">
+  <bpmn:startEvent id="S"/>
+  <bpmn:exclusiveGateway id="G" name="Amount?" default="Small"/>
+  <bpmn:task id="Auto" name="Approve automatically"/>
+  <bpmn:task id="Manual" name="Approve manually"/>
+  <bpmn:dataObjectReference id="Doc" name="Invoice"/>
+  <bpmn:task id="Archive" name="Archive">
+    <bpmn:dataInputAssociation><bpmn:sourceRef>Doc</bpmn:sourceRef></bpmn:dataInputAssociation>
+  </bpmn:task>
+  <bpmn:sequenceFlow id="F0" sourceRef="S" targetRef="G"/>
+  <bpmn:sequenceFlow id="Small" name="small" sourceRef="G" targetRef="Auto"/>
+  <bpmn:sequenceFlow id="Large" name="large" sourceRef="G" targetRef="Manual">
+    <bpmn:conditionExpression>amount &gt; 1000</bpmn:conditionExpression>
+  </bpmn:sequenceFlow>
+</bpmn:process>`),
+			want: `Process "Approval"
+Elements in flow order:
+1. start event (S)
+2. task "Archive" uses data object reference "Invoice"
+3. exclusive gateway "Amount?"
+4. task "Approve automatically"
+5. task "Approve manually"
+6. data object reference "Invoice"
+Sequence flows:
+- start event (S) -> exclusive gateway "Amount?"
+- exclusive gateway "Amount?" -> task "Approve automatically": "small" [default]
+- exclusive gateway "Amount?" -> task "Approve manually": "large" [amount > 1000]`,
+		},
+		"message flows": {
+			data: definitions(`
+<bpmn:collaboration id="Collab">
+  <bpmn:participant id="P_client" name="Client" processRef="Client_process"/>
+  <bpmn:participant id="P_api" name="API"/>
+  <bpmn:messageFlow id="M1" name="request" sourceRef="Send" targetRef="P_api"/>
+  <bpmn:messageFlow id="M2" sourceRef="P_api" targetRef="Send"/>
+</bpmn:collaboration>
+<bpmn:process id="Client_process">
+  <bpmn:sendTask id="Send" name="Send request"/>
+</bpmn:process>`),
+			want: `Pool "Client"
+Elements in flow order:
+1. send task "Send request"
+
+Pool "API"
+(black box pool)
+
+Message flows:
+- send task "Send request" -> pool "API": "request"
+- pool "API" -> send task "Send request"`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defs, err := Parse([]byte(tc.data))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if defs.Empty() {
+				t.Error("Empty = true for a named diagram")
+			}
+			if got := defs.Describe(); got != tc.want {
+				t.Errorf("Describe =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tests := map[string]struct {
+		data  string
+		empty bool
+	}{
+		"unnamed elements": {
+			data: definitions(`<bpmn:process id="P"><bpmn:startEvent id="S"/><bpmn:task id="T"/>
+				<bpmn:sequenceFlow id="F" sourceRef="S" targetRef="T"/></bpmn:process>`),
+			empty: true,
+		},
+		"unnamed pool": {
+			data:  definitions(`<bpmn:collaboration id="C"><bpmn:participant id="P"/></bpmn:collaboration>`),
+			empty: true,
+		},
+		"named subprocess element": {
+			data: definitions(`<bpmn:process id="P"><bpmn:subProcess id="Sub">
+				<bpmn:task id="T" name="Inner"/></bpmn:subProcess></bpmn:process>`),
+		},
+		"named flow": {
+			data: definitions(`<bpmn:process id="P"><bpmn:task id="A"/><bpmn:task id="B"/>
+				<bpmn:sequenceFlow id="F" name="next" sourceRef="A" targetRef="B"/></bpmn:process>`),
+		},
+		"named lane": {
+			data: definitions(`<bpmn:process id="P"><bpmn:laneSet><bpmn:lane id="L" name="Ops"/></bpmn:laneSet></bpmn:process>`),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defs, err := Parse([]byte(tc.data))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := defs.Empty(); got != tc.empty {
+				t.Errorf("Empty = %v, want %v", got, tc.empty)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"broken xml":   `<bpmn:definitions`,
+		"foreign root": `<mxfile/>`,
+		"no processes": definitions(``),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Fatal("Parse = nil error")
+			}
+		})
+	}
+}
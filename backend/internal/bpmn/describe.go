@@ -0,0 +1,278 @@
+package bpmn
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var camelCase = regexp.MustCompile(`([a-z])([A-Z])`)
+
+// Describe renders pools, lanes and flow elements in process order as text for the LLM prompt
+func (d *Definitions) Describe() string {
+	names := make(map[string]string)
+	for _, p := range d.Processes {
+		p.collectNames(names)
+	}
+	for _, p := range d.Participants {
+		names[p.ID] = fmt.Sprintf("pool %q", p.Name)
+	}
+
+	processes := make(map[string]Process, len(d.Processes))
+	for _, p := range d.Processes {
+		processes[p.ID] = p
+	}
+
+	var b strings.Builder
+	described := make(map[string]bool)
+	for _, participant := range d.Participants {
+		fmt.Fprintf(&b, "Pool %q\n", participant.Name)
+		if p, ok := processes[participant.ProcessRef]; ok {
+			p.describe(&b, names, "")
+			described[p.ID] = true
+		} else {
+			b.WriteString("(black box pool)\n")
+		}
+		b.WriteString("\n")
+	}
+
+	for _, p := range d.Processes {
+		if described[p.ID] {
+			continue
+		}
+		title := p.Name
+		if title == "" {
+			title = p.ID
+		}
+		fmt.Fprintf(&b, "Process %q\n", title)
+		p.describe(&b, names, "")
+		b.WriteString("\n")
+	}
+
+	if len(d.MessageFlows) > 0 {
+		b.WriteString("Message flows:\n")
+		for _, f := range d.MessageFlows {
+			describeFlow(&b, names, f, "")
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func (p Process) collectNames(names map[string]string) {
+	for _, el := range p.Elements {
+		names[el.ID] = el.displayName()
+		if el.Subprocess != nil {
+			el.Subprocess.collectNames(names)
+		}
+	}
+}
+
+func (p Process) describe(b *strings.Builder, names map[string]string, indent string) {
+	if len(p.Lanes) > 0 {
+		fmt.Fprintf(b, "%sLanes:\n", indent)
+		for _, lane := range p.Lanes {
+			members := make([]string, 0, len(lane.Elements))
+			for _, id := range lane.Elements {
+				members = append(members, nameOf(names, id))
+			}
+			fmt.Fprintf(b, "%s- %q: %s\n", indent, lane.Name, strings.Join(members, ", "))
+		}
+	}
+
+	defaults := make(map[string]bool)
+	for _, el := range p.Elements {
+		if el.Default != "" {
+			defaults[el.Default] = true
+		}
+	}
+
+	ordered := p.order()
+	if len(ordered) > 0 {
+		fmt.Fprintf(b, "%sElements in flow order:\n", indent)
+		for i, el := range ordered {
+			fmt.Fprintf(b, "%s%d. %s", indent, i+1, el.displayName())
+			if el.AttachedTo != "" {
+				fmt.Fprintf(b, " attached to %s", nameOf(names, el.AttachedTo))
+			}
+			if len(el.Documents) > 0 {
+				docs := make([]string, 0, len(el.Documents))
+				for _, id := range el.Documents {
+					if name, ok := names[id]; ok {
+						docs = append(docs, name)
+					}
+				}
+				if len(docs) > 0 {
+					fmt.Fprintf(b, " uses %s", strings.Join(docs, ", "))
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(p.Flows) > 0 {
+		position := make(map[string]int, len(ordered))
+		for i, el := range ordered {
+			position[el.ID] = i
+		}
+		flows := slices.Clone(p.Flows)
+		slices.SortStableFunc(flows, func(a, b Flow) int {
+			return position[a.Source] - position[b.Source]
+		})
+
+		fmt.Fprintf(b, "%sSequence flows:\n", indent)
+		for _, f := range flows {
+			if defaults[f.ID] && f.Condition == "" {
+				f.Condition = "default"
+			}
+			describeFlow(b, names, f, indent)
+		}
+	}
+
+	for _, el := range ordered {
+		if el.Subprocess == nil {
+			continue
+		}
+		fmt.Fprintf(b, "%sInside %s:\n", indent, el.displayName())
+		el.Subprocess.describe(b, names, indent+"  ")
+	}
+}
+
+// order walks sequence flows from elements without incoming flows,
+// elements unreachable from them keep their document order at the end
+func (p Process) order() []Element {
+	byID := make(map[string]Element, len(p.Elements))
+	incoming := make(map[string]int)
+	outgoing := make(map[string][]string)
+	for _, el := range p.Elements {
+		byID[el.ID] = el
+	}
+	for _, f := range p.Flows {
+		incoming[f.Target]++
+		outgoing[f.Source] = append(outgoing[f.Source], f.Target)
+	}
+
+	var (
+		result  []Element
+		queue   []string
+		visited = make(map[string]bool)
+	)
+
+	visit := func(id string) {
+		if visited[id] {
+			return
+		}
+		if _, ok := byID[id]; !ok {
+			return
+		}
+		visited[id] = true
+		queue = append(queue, id)
+	}
+
+	for _, el := range p.Elements {
+		if el.Kind == "startEvent" {
+			visit(el.ID)
+		}
+	}
+	for _, el := range p.Elements {
+		if incoming[el.ID] == 0 && el.Category != CategoryData && el.AttachedTo == "" {
+			visit(el.ID)
+		}
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		result = append(result, byID[id])
+
+		// boundary events are listed right after the activity they are attached to
+		for _, el := range p.Elements {
+			if el.AttachedTo == id {
+				visit(el.ID)
+			}
+		}
+		for _, next := range outgoing[id] {
+			visit(next)
+		}
+	}
+
+	for _, el := range p.Elements {
+		if !visited[el.ID] {
+			result = append(result, el)
+		}
+	}
+	return result
+}
+
+func describeFlow(b *strings.Builder, names map[string]string, f Flow, indent string) {
+	fmt.Fprintf(b, "%s- %s -> %s", indent, nameOf(names, f.Source), nameOf(names, f.Target))
+	if f.Name != "" {
+		fmt.Fprintf(b, ": %q", f.Name)
+	}
+	if f.Condition != "" {
+		fmt.Fprintf(b, " [%s]", f.Condition)
+	}
+	b.WriteString("\n")
+}
+
+func (el Element) displayName() string {
+	kind := strings.ToLower(camelCase.ReplaceAllString(el.Kind, "$1 $2"))
+	kind = strings.ReplaceAll(kind, "sub process", "subprocess")
+	if el.Definition != "" {
+		kind = fmt.Sprintf("%s %s", el.Definition, kind)
+	}
+	if el.Name == "" {
+		return fmt.Sprintf("%s (%s)", kind, el.ID)
+	}
+	return fmt.Sprintf("%s %q", kind, el.Name)
+}
+
+func nameOf(names map[string]string, id string) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return id
+}
+
+// Empty reports whether the definitions have no named pools, lanes, elements or flows worth describing
+func (d *Definitions) Empty() bool {
+	for _, p := range d.Participants {
+		if p.Name != "" {
+			return false
+		}
+	}
+	for _, f := range d.MessageFlows {
+		if f.Name != "" {
+			return false
+		}
+	}
+	for _, p := range d.Processes {
+		if !p.empty() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Process) empty() bool {
+	if p.Name != "" {
+		return false
+	}
+	for _, lane := range p.Lanes {
+		if lane.Name != "" {
+			return false
+		}
+	}
+	for _, el := range p.Elements {
+		if el.Name != "" || el.Subprocess != nil && !el.Subprocess.empty() {
+			return false
+		}
+	}
+	for _, f := range p.Flows {
+		if f.Name != "" {
+			return false
+		}
+	}
+	return true
+}
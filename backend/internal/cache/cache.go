@@ -0,0 +1,19 @@
+package cache
+
+import "errors"
+
+const (
+	tierMemory = "memory"
+	tierRedis  = "redis"
+	tierDisk   = "disk"
+)
+
+// ErrFull is returned by stores which never evict live entries when a value doesn't fit
+var ErrFull = errors.New("cache store is full")
+
+// Stats describes entries of a cache tier, bytes are counted the way the tier stores them
+type Stats struct {
+	Tier    string
+	Entries int64
+	Bytes   int64
+}
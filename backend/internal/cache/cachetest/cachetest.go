@@ -0,0 +1,259 @@
+// Package cachetest is a conformance suite for cache backends, backend tests call Run
+// and RunAdmin for backends with the admin methods
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+)
+
+// Cache mirrors service.Cache, the suite does not depend on the service package
+type Cache interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key string, value string) error
+}
+
+// AdminCache is a cache which can be listed and purged
+type AdminCache interface {
+	Cache
+	Peek(ctx context.Context, keys ...string) (map[string]string, error)
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, keys ...string) (int, error)
+	Stats(ctx context.Context, prefix string) ([]cache.Stats, error)
+}
+
+// Factory returns an empty cache whose entries expire after ttl
+type Factory func(t *testing.T, ttl time.Duration) Cache
+
+// AdminFactory returns an empty admin cache whose entries expire after ttl
+type AdminFactory func(t *testing.T, ttl time.Duration) AdminCache
+
+// Run checks the behaviour every backend must share. Caches are created with a long ttl
+// except for the expiration test, and must hold at least a few megabytes
+func Run(t *testing.T, newCache Factory) {
+	t.Run("Missing", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		if _, found, err := c.Get(context.Background(), "missing"); err != nil || found {
+			t.Fatalf("Get(missing) = found %v, err %v", found, err)
+		}
+	})
+
+	t.Run("SetGet", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		set(t, c, "key", "value")
+		expect(t, c, "key", "value")
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		set(t, c, "key", "first")
+		set(t, c, "key", "second")
+		expect(t, c, "key", "second")
+	})
+
+	t.Run("EmptyValue", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		set(t, c, "key", "")
+		expect(t, c, "key", "")
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		keys := []string{
+			"diagram-ai:v1:abc",
+			"with spaces/and/slashes",
+			"../escape",
+			"юникод 🔑",
+			strings.Repeat("k", 1024),
+		}
+		for i, key := range keys {
+			set(t, c, key, fmt.Sprintf("value-%d", i))
+		}
+		for i, key := range keys {
+			expect(t, c, key, fmt.Sprintf("value-%d", i))
+		}
+	})
+
+	t.Run("LargeValue", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		value := strings.Repeat("0123456789", 100_000)
+		set(t, c, "large", value)
+		expect(t, c, "large", value)
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		c := newCache(t, 50*time.Millisecond)
+		set(t, c, "key", "value")
+		time.Sleep(100 * time.Millisecond)
+		if _, found, err := c.Get(context.Background(), "key"); err != nil || found {
+			t.Fatalf("Get(expired) = found %v, err %v", found, err)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		var wg sync.WaitGroup
+		for i := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 50 {
+					key := fmt.Sprintf("key-%d", j%10)
+					if err := c.Set(context.Background(), key, fmt.Sprintf("%d-%d", i, j)); err != nil {
+						t.Errorf("Set(%s): %v", key, err)
+						return
+					}
+					if _, _, err := c.Get(context.Background(), key); err != nil {
+						t.Errorf("Get(%s): %v", key, err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		for j := range 10 {
+			if _, found, err := c.Get(context.Background(), fmt.Sprintf("key-%d", j)); err != nil || !found {
+				t.Fatalf("Get(key-%d) = found %v, err %v", j, found, err)
+			}
+		}
+	})
+}
+
+// RunAdmin checks listing, deletion and stats by prefix
+func RunAdmin(t *testing.T, newCache AdminFactory) {
+	fill := func(t *testing.T, c AdminCache) {
+		t.Helper()
+		set(t, c, "ns:a:1", "one")
+		set(t, c, "ns:a:2", "two")
+		set(t, c, "ns:b:1", "three")
+		set(t, c, "ns*:a:1", "glob")
+		set(t, c, "other:a:1", "four")
+	}
+
+	t.Run("Peek", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		fill(t, c)
+
+		values, err := c.Peek(context.Background(), "ns:a:1", "missing", "ns:b:1")
+		if err != nil {
+			t.Fatalf("Peek: %v", err)
+		}
+		if want := map[string]string{"ns:a:1": "one", "ns:b:1": "three"}; !maps.Equal(values, want) {
+			t.Fatalf("Peek = %v, want %v", values, want)
+		}
+		if values, err := c.Peek(context.Background()); err != nil || len(values) != 0 {
+			t.Fatalf("Peek() = %v, err %v", values, err)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		fill(t, c)
+
+		keys, err := c.Keys(context.Background(), "ns:a:")
+		if err != nil {
+			t.Fatalf("Keys: %v", err)
+		}
+		slices.Sort(keys)
+		if want := []string{"ns:a:1", "ns:a:2"}; !slices.Equal(keys, want) {
+			t.Fatalf("Keys(ns:a:) = %v, want %v", keys, want)
+		}
+
+		keys, err = c.Keys(context.Background(), "ns*")
+		if err != nil {
+			t.Fatalf("Keys: %v", err)
+		}
+		if want := []string{"ns*:a:1"}; !slices.Equal(keys, want) {
+			t.Fatalf("Keys(ns*) = %v, want %v", keys, want)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		fill(t, c)
+
+		deleted, err := c.Delete(context.Background(), "ns:a:1", "ns:b:1", "missing")
+		if err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if deleted != 2 {
+			t.Fatalf("Delete = %d, want 2", deleted)
+		}
+		for _, key := range []string{"ns:a:1", "ns:b:1"} {
+			if _, found, _ := c.Get(context.Background(), key); found {
+				t.Fatalf("Get(%s) found after Delete", key)
+			}
+		}
+		expect(t, c, "ns:a:2", "two")
+		expect(t, c, "other:a:1", "four")
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		c := newCache(t, time.Hour)
+		fill(t, c)
+
+		stats, err := c.Stats(context.Background(), "ns:")
+		if err != nil {
+			t.Fatalf("Stats: %v", err)
+		}
+		if len(stats) == 0 {
+			t.Fatal("Stats returned no tiers")
+		}
+		for _, tier := range stats {
+			if tier.Entries != 3 || tier.Bytes <= 0 {
+				t.Fatalf("Stats(ns:) tier %s = %d entries %d bytes, want 3 entries", tier.Tier, tier.Entries, tier.Bytes)
+			}
+		}
+	})
+
+	t.Run("ExpiredKeys", func(t *testing.T) {
+		c := newCache(t, 50*time.Millisecond)
+		set(t, c, "ns:a:1", "one")
+		time.Sleep(100 * time.Millisecond)
+
+		keys, err := c.Keys(context.Background(), "ns:")
+		if err != nil {
+			t.Fatalf("Keys: %v", err)
+		}
+		if len(keys) != 0 {
+			t.Fatalf("Keys(ns:) = %v after expiration", keys)
+		}
+	})
+}
+
+func set(t *testing.T, c Cache, key, value string) {
+	t.Helper()
+	if err := c.Set(context.Background(), key, value); err != nil {
+		t.Fatalf("Set(%q): %v", key, err)
+	}
+}
+
+func expect(t *testing.T, c Cache, key, want string) {
+	t.Helper()
+	got, found, err := c.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	if !found {
+		t.Fatalf("Get(%q): not found", key)
+	}
+	if got != want {
+		t.Fatalf("Get(%q) = %q, want %q", key, truncate(got), truncate(want))
+	}
+}
+
+func truncate(s string) string {
+	if len(s) > 64 {
+		return s[:64] + "..."
+	}
+	return s
+}
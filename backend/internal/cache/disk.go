@@ -0,0 +1,466 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	diskEntryExt = ".entry"
+	diskTempPref = "tmp-"
+
+	// expiration time and key length
+	diskHeaderSize = 8 + 4
+
+	// keys are far shorter, the bound keeps corrupted headers from allocating gigabytes
+	diskMaxKeyLen = 64 << 10
+)
+
+type diskEntry struct {
+	name      string
+	key       string
+	size      int64
+	expiresAt time.Time
+	usedAt    time.Time
+}
+
+// DiskCache keeps every entry in its own file named by the key hash. The index of files
+// is kept in memory, expired entries are swept periodically and least recently used ones
+// are evicted to fit maxBytes. Files are read and written outside the index lock, only
+// renames and removals which keep the index in sync with the directory are done under it
+type DiskCache struct {
+	logger   *log.Logger
+	dir      string
+	maxBytes int64
+	ttl      time.Duration
+	evict    bool
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*diskEntry
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDiskCache opens dir, entries left by a previous run are kept until they expire
+func NewDiskCache(logger *log.Logger, dir string, maxBytes int64, ttl, sweepInterval time.Duration) (*DiskCache, error) {
+	return newDiskCache(logger, dir, maxBytes, ttl, sweepInterval, true)
+}
+
+// NewDiskStore opens dir like NewDiskCache but never evicts live entries, Set fails with ErrFull
+// when the value doesn't fit maxBytes after expired entries are dropped
+func NewDiskStore(logger *log.Logger, dir string, maxBytes int64, ttl, sweepInterval time.Duration) (*DiskCache, error) {
+	return newDiskCache(logger, dir, maxBytes, ttl, sweepInterval, false)
+}
+
+func newDiskCache(logger *log.Logger, dir string, maxBytes int64, ttl, sweepInterval time.Duration, evict bool) (*DiskCache, error) {
+	if sweepInterval <= 0 {
+		return nil, fmt.Errorf("sweep interval must be positive, got %s", sweepInterval)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	d := &DiskCache{
+		logger:   logger,
+		dir:      dir,
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		evict:    evict,
+		entries:  make(map[string]*diskEntry),
+		stop:     make(chan struct{}),
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	d.wg.Add(1)
+	go d.sweeper(sweepInterval)
+	return d, nil
+}
+
+func (d *DiskCache) Get(_ context.Context, key string) (string, bool, error) {
+	return d.get(key, true)
+}
+
+// Peek reads the entries without making them recently used, missing keys are left out
+func (d *DiskCache) Peek(_ context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, found, err := d.get(key, false)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (d *DiskCache) get(key string, touch bool) (string, bool, error) {
+	d.mu.Lock()
+	entry, ok := d.entries[diskName(key)]
+	if ok && time.Now().After(entry.expiresAt) {
+		d.remove(entry)
+		ok = false
+	}
+	d.mu.Unlock()
+	if !ok {
+		return "", false, nil
+	}
+
+	// the file may be replaced or removed meanwhile, the stored key tells whose value was read
+	data, err := os.ReadFile(d.path(entry.name))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		d.drop(entry)
+		return "", false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	storedKey, value, expiresAt, err := decodeDiskEntry(data)
+	if err != nil || storedKey != key {
+		d.drop(entry)
+		return "", false, err
+	}
+	if time.Now().After(expiresAt) {
+		return "", false, nil
+	}
+
+	if touch {
+		d.mu.Lock()
+		entry.usedAt = time.Now()
+		d.mu.Unlock()
+	}
+	return value, true, nil
+}
+
+// Set writes the entry atomically, values larger than maxBytes are not stored
+func (d *DiskCache) Set(_ context.Context, key string, value string) error {
+	if len(key) > diskMaxKeyLen {
+		return fmt.Errorf("cache key is longer than %d bytes", diskMaxKeyLen)
+	}
+	expiresAt := time.Now().Add(d.ttl)
+	data := encodeDiskEntry(key, value, expiresAt)
+	size := int64(len(data))
+	name := diskName(key)
+
+	if size > d.maxBytes {
+		if !d.evict {
+			return ErrFull
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if entry, ok := d.entries[name]; ok {
+			d.remove(entry)
+		}
+		return nil
+	}
+
+	tmp, err := d.writeTemp(data)
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	old := d.entries[name]
+	if !d.evict && !d.fits(old, size) {
+		return ErrFull
+	}
+	if err := os.Rename(tmp, d.path(name)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if old != nil {
+		d.forget(old)
+	}
+	d.entries[name] = &diskEntry{
+		name:      name,
+		key:       key,
+		size:      size,
+		expiresAt: expiresAt,
+		usedAt:    time.Now(),
+	}
+	d.size += size
+
+	d.evictLRU()
+	return nil
+}
+
+// Keys returns keys of live entries starting with prefix
+func (d *DiskCache) Keys(_ context.Context, prefix string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var keys []string
+	for _, entry := range d.entries {
+		if strings.HasPrefix(entry.key, prefix) && !now.After(entry.expiresAt) {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys, nil
+}
+
+// Delete removes the entries and returns how many of them existed
+func (d *DiskCache) Delete(_ context.Context, keys ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if entry, ok := d.entries[diskName(key)]; ok {
+			d.remove(entry)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Stats counts live entries starting with prefix and their file sizes
+func (d *DiskCache) Stats(_ context.Context, prefix string) ([]Stats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := Stats{Tier: tierDisk}
+	now := time.Now()
+	for _, entry := range d.entries {
+		if strings.HasPrefix(entry.key, prefix) && !now.After(entry.expiresAt) {
+			stats.Entries++
+			stats.Bytes += entry.size
+		}
+	}
+	return []Stats{stats}, nil
+}
+
+// Close stops the sweeper, entries stay on disk
+func (d *DiskCache) Close() {
+	close(d.stop)
+	d.wg.Wait()
+}
+
+// writeTemp writes data to a temp file in dir, it is renamed to the entry file under the lock
+func (d *DiskCache) writeTemp(data []byte) (string, error) {
+	tmp, err := os.CreateTemp(d.dir, diskTempPref+"*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// load indexes existing files, the last modification is taken as the last use
+func (d *DiskCache) load() error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache dir: %w", err)
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, diskTempPref) {
+			os.Remove(d.path(name))
+			continue
+		}
+		if !strings.HasSuffix(name, diskEntryExt) {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		key, expiresAt, err := readDiskHeader(d.path(name))
+		if err != nil {
+			os.Remove(d.path(name))
+			continue
+		}
+
+		d.entries[name] = &diskEntry{
+			name:      name,
+			key:       key,
+			size:      info.Size(),
+			expiresAt: expiresAt,
+			usedAt:    info.ModTime(),
+		}
+		d.size += info.Size()
+	}
+
+	d.sweep()
+	return nil
+}
+
+func (d *DiskCache) sweeper(interval time.Duration) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.sweep()
+			d.mu.Unlock()
+		}
+	}
+}
+
+// sweep removes expired entries and evicts to fit maxBytes
+func (d *DiskCache) sweep() {
+	if removed := d.removeExpired(); removed > 0 {
+		d.logger.Printf("disk cache %s: %d expired entries removed\n", d.dir, removed)
+	}
+	d.evictLRU()
+}
+
+func (d *DiskCache) removeExpired() int {
+	now := time.Now()
+	removed := 0
+	for _, entry := range d.entries {
+		if now.After(entry.expiresAt) {
+			d.remove(entry)
+			removed++
+		}
+	}
+	return removed
+}
+
+// fits drops expired entries when an entry of size replacing old exceeds maxBytes
+func (d *DiskCache) fits(old *diskEntry, size int64) bool {
+	need := func() int64 {
+		if old != nil && d.entries[old.name] == old {
+			return d.size - old.size + size
+		}
+		return d.size + size
+	}
+	if need() <= d.maxBytes {
+		return true
+	}
+	d.removeExpired()
+	return need() <= d.maxBytes
+}
+
+func (d *DiskCache) evictLRU() {
+	if !d.evict || d.size <= d.maxBytes {
+		return
+	}
+
+	entries := make([]*diskEntry, 0, len(d.entries))
+	for _, entry := range d.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b *diskEntry) int {
+		return a.usedAt.Compare(b.usedAt)
+	})
+
+	for _, entry := range entries {
+		if d.size <= d.maxBytes {
+			return
+		}
+		d.remove(entry)
+	}
+}
+
+// drop removes the entry unless it was replaced after the caller looked it up
+func (d *DiskCache) drop(entry *diskEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries[entry.name] == entry {
+		d.remove(entry)
+	}
+}
+
+func (d *DiskCache) remove(entry *diskEntry) {
+	if err := os.Remove(d.path(entry.name)); err != nil && !os.IsNotExist(err) {
+		d.logger.Printf("failed to remove cache entry: %v\n", err)
+	}
+	d.forget(entry)
+}
+
+// forget removes the entry from the index, the file is kept
+func (d *DiskCache) forget(entry *diskEntry) {
+	delete(d.entries, entry.name)
+	d.size -= entry.size
+}
+
+func (d *DiskCache) path(name string) string {
+	return filepath.Join(d.dir, name)
+}
+
+func diskName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + diskEntryExt
+}
+
+// encodeDiskEntry lays out the expiration in unix nanoseconds, the key length, the key and the value
+func encodeDiskEntry(key, value string, expiresAt time.Time) []byte {
+	data := make([]byte, diskHeaderSize, diskHeaderSize+len(key)+len(value))
+	binary.BigEndian.PutUint64(data, uint64(expiresAt.UnixNano()))
+	binary.BigEndian.PutUint32(data[8:], uint32(len(key)))
+	data = append(data, key...)
+	return append(data, value...)
+}
+
+func decodeDiskEntry(data []byte) (key, value string, expiresAt time.Time, err error) {
+	if len(data) < diskHeaderSize {
+		return "", "", time.Time{}, fmt.Errorf("cache entry is truncated")
+	}
+	expiresAt = time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	keyLen := int(binary.BigEndian.Uint32(data[8:]))
+	if len(data) < diskHeaderSize+keyLen {
+		return "", "", time.Time{}, fmt.Errorf("cache entry is truncated")
+	}
+	key = string(data[diskHeaderSize : diskHeaderSize+keyLen])
+	value = string(data[diskHeaderSize+keyLen:])
+	return key, value, expiresAt, nil
+}
+
+// readDiskHeader reads the expiration and the key without the value
+func readDiskHeader(path string) (string, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer f.Close()
+
+	header := make([]byte, diskHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return "", time.Time{}, err
+	}
+	keyLen := binary.BigEndian.Uint32(header[8:])
+	if keyLen > diskMaxKeyLen {
+		return "", time.Time{}, fmt.Errorf("cache entry key length %d exceeds %d", keyLen, diskMaxKeyLen)
+	}
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(f, key); err != nil {
+		return "", time.Time{}, err
+	}
+	return string(key), time.Unix(0, int64(binary.BigEndian.Uint64(header))), nil
+}
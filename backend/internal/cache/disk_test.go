@@ -0,0 +1,162 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache/cachetest"
+)
+
+var testLogger = log.New(io.Discard, "", 0)
+
+func openDisk(t *testing.T, open func(*log.Logger, string, int64, time.Duration, time.Duration) (*cache.DiskCache, error),
+	dir string, maxBytes int64, ttl time.Duration) *cache.DiskCache {
+	t.Helper()
+	d, err := open(testLogger, dir, maxBytes, ttl, time.Minute)
+	if err != nil {
+		t.Fatalf("open disk cache: %v", err)
+	}
+	t.Cleanup(d.Close)
+	return d
+}
+
+func TestDiskCache(t *testing.T) {
+	newCache := func(t *testing.T, ttl time.Duration) cachetest.AdminCache {
+		return openDisk(t, cache.NewDiskCache, t.TempDir(), testMaxBytes, ttl)
+	}
+	cachetest.Run(t, func(t *testing.T, ttl time.Duration) cachetest.Cache {
+		return newCache(t, ttl)
+	})
+	cachetest.RunAdmin(t, newCache)
+}
+
+func TestDiskStore(t *testing.T) {
+	newCache := func(t *testing.T, ttl time.Duration) cachetest.AdminCache {
+		return openDisk(t, cache.NewDiskStore, t.TempDir(), testMaxBytes, ttl)
+	}
+	cachetest.Run(t, func(t *testing.T, ttl time.Duration) cachetest.Cache {
+		return newCache(t, ttl)
+	})
+	cachetest.RunAdmin(t, newCache)
+}
+
+func TestDiskCacheReload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	d, err := cache.NewDiskCache(testLogger, dir, testMaxBytes, time.Hour, time.Minute)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	if err := d.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	d.Close()
+
+	d = openDisk(t, cache.NewDiskCache, dir, testMaxBytes, time.Hour)
+	if value, found, err := d.Get(ctx, "key"); err != nil || !found || value != "value" {
+		t.Fatalf("Get after reopen = %q, found %v, err %v", value, found, err)
+	}
+}
+
+func TestDiskCacheCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// the header claims a 4GB key
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint64(header, uint64(time.Now().Add(time.Hour).UnixNano()))
+	binary.BigEndian.PutUint32(header[8:], 1<<32-1)
+	files := map[string][]byte{
+		"huge.entry":      header,
+		"truncated.entry": header[:5],
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := openDisk(t, cache.NewDiskCache, dir, testMaxBytes, time.Hour)
+	keys, err := d.Keys(context.Background(), "")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("Keys = %v, err %v, want no entries", keys, err)
+	}
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("corrupt file %s was kept", name)
+		}
+	}
+}
+
+func TestDiskCacheEvicts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// every entry takes the 12 byte header, a one byte key and the value
+	d := openDisk(t, cache.NewDiskCache, dir, 50, time.Hour)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := d.Set(ctx, key, "1234567890"); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, found, _ := d.Get(ctx, "a"); found {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	if _, found, _ := d.Get(ctx, "c"); !found {
+		t.Fatal("last entry was evicted")
+	}
+
+	if err := d.Set(ctx, "c", strings.Repeat("x", 100)); err != nil {
+		t.Fatalf("Set(c, large): %v", err)
+	}
+	if _, found, _ := d.Get(ctx, "c"); found {
+		t.Fatal("entry larger than the cache was stored")
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("%d files left, want 1", len(files))
+	}
+}
+
+func TestDiskStoreFull(t *testing.T) {
+	ctx := context.Background()
+	d := openDisk(t, cache.NewDiskStore, t.TempDir(), 50, 50*time.Millisecond)
+
+	for _, key := range []string{"a", "b"} {
+		if err := d.Set(ctx, key, "1234567890"); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+	}
+	if err := d.Set(ctx, "c", "1234567890"); !errors.Is(err, cache.ErrFull) {
+		t.Fatalf("Set(c) = %v, want ErrFull", err)
+	}
+	if err := d.Set(ctx, "a", strings.Repeat("x", 100)); !errors.Is(err, cache.ErrFull) {
+		t.Fatalf("Set(a, large) = %v, want ErrFull", err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if value, found, _ := d.Get(ctx, key); !found || value != "1234567890" {
+			t.Fatalf("Get(%s) = %q, found %v after a failed Set", key, value, found)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := d.Set(ctx, "c", "1234567890"); err != nil {
+		t.Fatalf("Set(c) after expiration: %v", err)
+	}
+}
+
+func TestDiskCacheSweepInterval(t *testing.T) {
+	if _, err := cache.NewDiskCache(testLogger, t.TempDir(), testMaxBytes, time.Hour, 0); err == nil {
+		t.Fatal("NewDiskCache accepted a zero sweep interval")
+	}
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// MemoryCache is an in-process LRU bounded by the total size of keys and values
+type MemoryCache struct {
+	maxBytes int64
+	ttl      time.Duration
+	evict    bool
+
+	mu    sync.Mutex
+	size  int64
+	order *list.List
+	items map[string]*list.Element
+}
+
+func NewMemoryCache(maxBytes int64, ttl time.Duration) *MemoryCache {
+	return newMemoryCache(maxBytes, ttl, true)
+}
+
+// NewMemoryStore is a MemoryCache which never evicts live entries, Set fails with ErrFull
+// when the value doesn't fit maxBytes after expired entries are dropped
+func NewMemoryStore(maxBytes int64, ttl time.Duration) *MemoryCache {
+	return newMemoryCache(maxBytes, ttl, false)
+}
+
+func newMemoryCache(maxBytes int64, ttl time.Duration, evict bool) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		evict:    evict,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) (string, bool, error) {
+	value, found := m.get(key, true)
+	return value, found, nil
+}
+
+// Peek reads the entries without making them recently used, missing keys are left out
+func (m *MemoryCache) Peek(_ context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, found := m.get(key, false); found {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (m *MemoryCache) get(key string, touch bool) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return "", false
+	}
+	item := el.Value.(*memoryItem)
+	if time.Now().After(item.expiresAt) {
+		m.remove(el)
+		return "", false
+	}
+
+	if touch {
+		m.order.MoveToFront(el)
+	}
+	return item.value, true
+}
+
+// Set stores the value, least recently used entries are evicted to fit maxBytes.
+// Values larger than maxBytes are not stored
+func (m *MemoryCache) Set(_ context.Context, key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	itemSize := int64(len(key) + len(value))
+	if !m.evict && !m.fits(key, itemSize) {
+		return ErrFull
+	}
+
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+	if itemSize > m.maxBytes {
+		return nil
+	}
+
+	item := &memoryItem{key: key, value: value, expiresAt: time.Now().Add(m.ttl)}
+	m.items[key] = m.order.PushFront(item)
+	m.size += itemSize
+
+	for m.size > m.maxBytes {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+// Keys returns keys of live entries starting with prefix
+func (m *MemoryCache) Keys(_ context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var keys []string
+	for key, el := range m.items {
+		if strings.HasPrefix(key, prefix) && !now.After(el.Value.(*memoryItem).expiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Delete removes the entries and returns how many of them existed
+func (m *MemoryCache) Delete(_ context.Context, keys ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Stats counts live entries starting with prefix and the size of their keys and values
+func (m *MemoryCache) Stats(_ context.Context, prefix string) ([]Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := Stats{Tier: tierMemory}
+	now := time.Now()
+	for key, el := range m.items {
+		item := el.Value.(*memoryItem)
+		if strings.HasPrefix(key, prefix) && !now.After(item.expiresAt) {
+			stats.Entries++
+			stats.Bytes += int64(len(item.key) + len(item.value))
+		}
+	}
+	return []Stats{stats}, nil
+}
+
+// Size returns the total size of stored keys and values in bytes
+func (m *MemoryCache) Size() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.size
+}
+
+// fits drops expired entries when the item replacing the one under key exceeds maxBytes
+func (m *MemoryCache) fits(key string, itemSize int64) bool {
+	size := func() int64 {
+		if el, ok := m.items[key]; ok {
+			item := el.Value.(*memoryItem)
+			return m.size - int64(len(item.key)+len(item.value)) + itemSize
+		}
+		return m.size + itemSize
+	}
+	if size() <= m.maxBytes {
+		return true
+	}
+
+	now := time.Now()
+	for _, el := range m.items {
+		if now.After(el.Value.(*memoryItem).expiresAt) {
+			m.remove(el)
+		}
+	}
+	return size() <= m.maxBytes
+}
+
+func (m *MemoryCache) remove(el *list.Element) {
+	item := m.order.Remove(el).(*memoryItem)
+	delete(m.items, item.key)
+	m.size -= int64(len(item.key) + len(item.value))
+}
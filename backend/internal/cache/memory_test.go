@@ -0,0 +1,109 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache/cachetest"
+)
+
+const testMaxBytes = 16 << 20
+
+func TestMemoryCache(t *testing.T) {
+	newCache := func(t *testing.T, ttl time.Duration) cachetest.AdminCache {
+		return cache.NewMemoryCache(testMaxBytes, ttl)
+	}
+	cachetest.Run(t, func(t *testing.T, ttl time.Duration) cachetest.Cache {
+		return newCache(t, ttl)
+	})
+	cachetest.RunAdmin(t, newCache)
+}
+
+func TestMemoryStore(t *testing.T) {
+	newCache := func(t *testing.T, ttl time.Duration) cachetest.AdminCache {
+		return cache.NewMemoryStore(testMaxBytes, ttl)
+	}
+	cachetest.Run(t, func(t *testing.T, ttl time.Duration) cachetest.Cache {
+		return newCache(t, ttl)
+	})
+	cachetest.RunAdmin(t, newCache)
+}
+
+func TestMemoryCacheEvicts(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemoryCache(20, time.Hour)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Set(ctx, key, "123456789"); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+	}
+	if _, found, _ := c.Get(ctx, "a"); found {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	if _, found, _ := c.Get(ctx, "c"); !found {
+		t.Fatal("last entry was evicted")
+	}
+
+	if err := c.Set(ctx, "large", strings.Repeat("x", 100)); err != nil {
+		t.Fatalf("Set(large): %v", err)
+	}
+	if _, found, _ := c.Get(ctx, "large"); found {
+		t.Fatal("entry larger than the cache was stored")
+	}
+}
+
+func TestMemoryStoreFull(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemoryStore(20, 50*time.Millisecond)
+
+	if err := c.Set(ctx, "a", "123456789"); err != nil {
+		t.Fatalf("Set(a): %v", err)
+	}
+	if err := c.Set(ctx, "b", "123456789"); err != nil {
+		t.Fatalf("Set(b): %v", err)
+	}
+	if err := c.Set(ctx, "c", "123456789"); !errors.Is(err, cache.ErrFull) {
+		t.Fatalf("Set(c) = %v, want ErrFull", err)
+	}
+	if err := c.Set(ctx, "a", strings.Repeat("x", 100)); !errors.Is(err, cache.ErrFull) {
+		t.Fatalf("Set(a, large) = %v, want ErrFull", err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if value, found, _ := c.Get(ctx, key); !found || value != "123456789" {
+			t.Fatalf("Get(%s) = %q, found %v after a failed Set", key, value, found)
+		}
+	}
+	if err := c.Set(ctx, "b", "987654321"); err != nil {
+		t.Fatalf("Set(b) overwrite: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := c.Set(ctx, "c", "123456789"); err != nil {
+		t.Fatalf("Set(c) after expiration: %v", err)
+	}
+}
+
+func TestMemoryCachePeekKeepsOrder(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemoryCache(20, time.Hour)
+
+	for _, key := range []string{"a", "b"} {
+		if err := c.Set(ctx, key, "123456789"); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+	}
+	if values, _ := c.Peek(ctx, "a"); values["a"] == "" {
+		t.Fatal("Peek(a) missed")
+	}
+	if err := c.Set(ctx, "c", "123456789"); err != nil {
+		t.Fatalf("Set(c): %v", err)
+	}
+	if _, found, _ := c.Get(ctx, "a"); found {
+		t.Fatal("peeked entry was made recently used")
+	}
+}
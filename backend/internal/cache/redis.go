@@ -2,11 +2,15 @@ package cache
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const redisScanCount = 500
+
 type RedisCache struct {
 	client *redis.Client
 	ttl    time.Duration
@@ -35,6 +39,89 @@ func (r *RedisCache) Get(ctx context.Context, key string) (string, bool, error)
 	return val, true, nil
 }
 
+// Peek reads the entries with MGET batches, reads don't change what Redis keeps. Missing keys are left out
+func (r *RedisCache) Peek(ctx context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for batch := range slices.Chunk(keys, redisScanCount) {
+		results, err := r.client.MGet(ctx, batch...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range results {
+			if value, ok := result.(string); ok {
+				values[batch[i]] = value
+			}
+		}
+	}
+	return values, nil
+}
+
 func (r *RedisCache) Set(ctx context.Context, key string, value string) error {
 	return r.client.Set(ctx, key, value, r.ttl).Err()
 }
+
+// Keys scans keys starting with prefix, glob characters of the prefix are matched literally
+func (r *RedisCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, redisPattern(prefix), redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Delete removes the entries and returns how many of them existed
+func (r *RedisCache) Delete(ctx context.Context, keys ...string) (int, error) {
+	deleted := 0
+	for batch := range slices.Chunk(keys, redisScanCount) {
+		n, err := r.client.Del(ctx, batch...).Result()
+		deleted += int(n)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Stats counts entries starting with prefix, bytes are reported by MEMORY USAGE
+func (r *RedisCache) Stats(ctx context.Context, prefix string) ([]Stats, error) {
+	keys, err := r.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := Stats{Tier: tierRedis}
+	for batch := range slices.Chunk(keys, redisScanCount) {
+		pipe := r.client.Pipeline()
+		usages := make([]*redis.IntCmd, len(batch))
+		for i, key := range batch {
+			usages[i] = pipe.MemoryUsage(ctx, key)
+		}
+		// keys expired since the scan are reported as nil
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+		for _, usage := range usages {
+			if bytes, err := usage.Result(); err == nil {
+				stats.Entries++
+				stats.Bytes += bytes
+			}
+		}
+	}
+	return []Stats{stats}, nil
+}
+
+func redisPattern(prefix string) string {
+	var b strings.Builder
+	for _, c := range prefix {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('*')
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package cache_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache/cachetest"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTestDB is flushed before every test, REDIS_TEST_ADDR must point to a disposable server
+const redisTestDB = 15
+
+func TestRedisCache(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr, DB: redisTestDB})
+	t.Cleanup(func() { client.Close() })
+
+	newCache := func(t *testing.T, ttl time.Duration) cachetest.AdminCache {
+		if err := client.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatalf("flush redis: %v", err)
+		}
+		return cache.NewRedisCache(addr, "", redisTestDB, ttl)
+	}
+	cachetest.Run(t, func(t *testing.T, ttl time.Duration) cachetest.Cache {
+		return newCache(t, ttl)
+	})
+	cachetest.RunAdmin(t, newCache)
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+)
+
+type remoteCache interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Peek(ctx context.Context, keys ...string) (map[string]string, error)
+	Set(ctx context.Context, key string, value string) error
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, keys ...string) (int, error)
+	Stats(ctx context.Context, prefix string) ([]Stats, error)
+}
+
+// TieredCache looks up the memory tier first and Redis on a miss. Redis errors are not
+// returned: Redis is skipped for retryInterval and the memory tier keeps serving meanwhile
+type TieredCache struct {
+	logger        *log.Logger
+	memory        *MemoryCache
+	remote        remoteCache
+	retryInterval time.Duration
+
+	mu        sync.Mutex
+	downUntil time.Time
+	down      bool
+}
+
+func NewTieredCache(logger *log.Logger, memory *MemoryCache, remote remoteCache, retryInterval time.Duration) *TieredCache {
+	metrics.CacheRemoteAvailable(true)
+	return &TieredCache{
+		logger:        logger,
+		memory:        memory,
+		remote:        remote,
+		retryInterval: retryInterval,
+	}
+}
+
+func (t *TieredCache) Get(ctx context.Context, key string) (string, bool, error) {
+	if value, found, _ := t.memory.Get(ctx, key); found {
+		metrics.CacheRequest(tierMemory, "hit")
+		return value, true, nil
+	}
+	metrics.CacheRequest(tierMemory, "miss")
+
+	if !t.remoteAvailable() {
+		return "", false, nil
+	}
+
+	value, found, err := t.remote.Get(ctx, key)
+	switch {
+	case err != nil:
+		t.remoteFailed(ctx, err)
+		return "", false, nil
+	case !found:
+		t.remoteSucceeded()
+		metrics.CacheRequest(tierRedis, "miss")
+		return "", false, nil
+	}
+
+	t.remoteSucceeded()
+	metrics.CacheRequest(tierRedis, "hit")
+	t.setMemory(ctx, key, value)
+	return value, true, nil
+}
+
+func (t *TieredCache) Set(ctx context.Context, key string, value string) error {
+	t.setMemory(ctx, key, value)
+
+	if !t.remoteAvailable() {
+		return nil
+	}
+	if err := t.remote.Set(ctx, key, value); err != nil {
+		t.remoteFailed(ctx, err)
+		return nil
+	}
+	t.remoteSucceeded()
+	return nil
+}
+
+// Peek reads the memory tier, then Redis for the rest, without promoting Redis entries into memory,
+// so admin scans don't evict hot entries. Unlike Get, Redis errors are returned
+func (t *TieredCache) Peek(ctx context.Context, keys ...string) (map[string]string, error) {
+	values, _ := t.memory.Peek(ctx, keys...)
+	var missing []string
+	for _, key := range keys {
+		if _, found := values[key]; !found {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	remoteValues, err := t.remote.Peek(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range remoteValues {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// Keys returns keys of both tiers. Unlike Get and Set, admin operations return Redis errors
+func (t *TieredCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys, _ := t.memory.Keys(ctx, prefix)
+	remoteKeys, err := t.remote.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, key := range remoteKeys {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Delete removes the keys from Redis and the memory tier of this process. Memory tiers of other
+// replicas are not notified and keep their copies until they expire.
+// Every entry is written to Redis, so its count is returned
+func (t *TieredCache) Delete(ctx context.Context, keys ...string) (int, error) {
+	t.memory.Delete(ctx, keys...)
+	metrics.CacheMemoryBytes(t.memory.Size())
+	return t.remote.Delete(ctx, keys...)
+}
+
+func (t *TieredCache) Stats(ctx context.Context, prefix string) ([]Stats, error) {
+	stats, _ := t.memory.Stats(ctx, prefix)
+	remoteStats, err := t.remote.Stats(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return append(stats, remoteStats...), nil
+}
+
+func (t *TieredCache) setMemory(ctx context.Context, key, value string) {
+	t.memory.Set(ctx, key, value)
+	metrics.CacheMemoryBytes(t.memory.Size())
+}
+
+func (t *TieredCache) remoteAvailable() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.down || !time.Now().Before(t.downUntil)
+}
+
+func (t *TieredCache) remoteFailed(ctx context.Context, err error) {
+	// the request is gone, it says nothing about redis
+	if ctx.Err() != nil {
+		return
+	}
+	metrics.CacheRequest(tierRedis, "error")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.down {
+		t.logger.Printf("redis cache is unavailable, using memory only: %v\n", err)
+		metrics.CacheRemoteAvailable(false)
+	}
+	t.down = true
+	t.downUntil = time.Now().Add(t.retryInterval)
+}
+
+func (t *TieredCache) remoteSucceeded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.down {
+		t.logger.Println("redis cache is available again")
+		metrics.CacheRemoteAvailable(true)
+	}
+	t.down = false
+}
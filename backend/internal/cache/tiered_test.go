@@ -0,0 +1,47 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache/cachetest"
+)
+
+// TestTieredCache runs the suite with a memory store in place of Redis,
+// RedisCache itself is checked by TestRedisCache
+func TestTieredCache(t *testing.T) {
+	newCache := func(t *testing.T, ttl time.Duration) cachetest.AdminCache {
+		return cache.NewTieredCache(testLogger, cache.NewMemoryCache(testMaxBytes, ttl), cache.NewMemoryStore(testMaxBytes, ttl), time.Minute)
+	}
+	cachetest.Run(t, func(t *testing.T, ttl time.Duration) cachetest.Cache {
+		return newCache(t, ttl)
+	})
+	cachetest.RunAdmin(t, newCache)
+}
+
+func TestTieredCachePeekDoesNotPromote(t *testing.T) {
+	ctx := context.Background()
+	memory := cache.NewMemoryCache(testMaxBytes, time.Hour)
+	remote := cache.NewMemoryCache(testMaxBytes, time.Hour)
+	c := cache.NewTieredCache(testLogger, memory, remote, time.Minute)
+
+	if err := remote.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("remote Set: %v", err)
+	}
+	values, err := c.Peek(ctx, "key")
+	if err != nil || values["key"] != "value" {
+		t.Fatalf("Peek(key) = %v, err %v", values, err)
+	}
+	if _, found, _ := memory.Get(ctx, "key"); found {
+		t.Fatal("Peek promoted the remote entry into the memory tier")
+	}
+
+	if _, found, _ := c.Get(ctx, "key"); !found {
+		t.Fatal("Get(key) missed")
+	}
+	if _, found, _ := memory.Get(ctx, "key"); !found {
+		t.Fatal("Get didn't promote the remote entry into the memory tier")
+	}
+}
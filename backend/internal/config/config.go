@@ -1,16 +1,83 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
 )
 
 type Config struct {
-	Server      ServerConfig
-	OpenAI      OpenAIConfig
-	RedisConfig RedisConfig
-	CacheEnable bool `env:"CACHE_ENABLE"`
+	Server        ServerConfig
+	OpenAI        OpenAIConfig
+	RedisConfig   RedisConfig
+	Jobs          JobsConfig
+	Batch         BatchConfig
+	Sessions      SessionsConfig
+	Stream        StreamConfig
+	Queue         QueueConfig
+	Upload        UploadConfig
+	CacheTiers    CacheTiersConfig
+	CacheDisk     DiskCacheConfig
+	SemanticCache SemanticCacheConfig
+	CacheEnable   bool   `env:"CACHE_ENABLE"`
+	CacheVersion  string `env:"CACHE_VERSION" envDefault:"v1"`
+
+	// storage of the explain cache, jobs and sessions: "redis", "disk" or "memory"
+	CacheBackend string `env:"CACHE_BACKEND" envDefault:"redis"`
+
+	// prefix of cached answer, job and session keys, separates deployments sharing a store
+	CacheNamespace string `env:"CACHE_NAMESPACE" envDefault:"diagram-ai"`
+
+	// enables the cache admin API, requests must send "Authorization: Bearer <token>"
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	// preprocessing of drawio, bpmn, svg and pdf files is cached apart from answers,
+	// so new prompts about a file skip rendering. Larger outputs are not cached, 0 disables it
+	CachePreprocessMaxBytes int `env:"CACHE_PREPROCESS_MAX_BYTES" envDefault:"16777216"`
+
+	// delay between deltas of replayed cached answers, 0 sends them at once
+	CacheReplayDelay time.Duration `env:"CACHE_REPLAY_DELAY" envDefault:"0s"`
+}
+
+// CacheTiersConfig configures the in-memory LRU in front of the redis cache, MemoryMaxBytes 0 disables it.
+// Redis is skipped for RedisRetryInterval after an error, the memory tier keeps serving meanwhile
+type CacheTiersConfig struct {
+	MemoryMaxBytes     int64         `env:"CACHE_MEMORY_MAX_BYTES" envDefault:"67108864"`
+	MemoryTTL          time.Duration `env:"CACHE_MEMORY_TTL" envDefault:"10m"`
+	RedisRetryInterval time.Duration `env:"CACHE_REDIS_RETRY_INTERVAL" envDefault:"10s"`
+}
+
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendDisk   = "disk"
+	CacheBackendMemory = "memory"
+)
+
+// DiskCacheConfig configures the disk backend, every store gets its own subdirectory of Dir
+// limited by MaxBytes. TTL applies to the explain cache, jobs and sessions keep their own.
+// The explain cache evicts least recently used entries, jobs and sessions are never evicted
+// and fail to save once their directory is full
+type DiskCacheConfig struct {
+	Dir           string        `env:"CACHE_DISK_DIR" envDefault:"./data/cache"`
+	MaxBytes      int64         `env:"CACHE_DISK_MAX_BYTES" envDefault:"268435456"`
+	TTL           time.Duration `env:"CACHE_DISK_TTL" envDefault:"24h"`
+	SweepInterval time.Duration `env:"CACHE_DISK_SWEEP_INTERVAL" envDefault:"1m"`
+}
+
+// SemanticCacheConfig reuses cached answers to similar prompts about the same file with the same
+// request settings. Prompts are embedded by Model at BaseURL (OPENAI_BASE_URL when empty), an answer
+// is reused when the cosine similarity of prompts is at least Threshold. The last MaxPrompts prompts
+// are kept per file and settings. Requires CACHE_ENABLE
+type SemanticCacheConfig struct {
+	Enable     bool          `env:"SEMANTIC_CACHE_ENABLE" envDefault:"false"`
+	BaseURL    string        `env:"SEMANTIC_CACHE_BASE_URL"`
+	Model      string        `env:"SEMANTIC_CACHE_MODEL" envDefault:"default"`
+	Threshold  float64       `env:"SEMANTIC_CACHE_THRESHOLD" envDefault:"0.92"`
+	MaxPrompts int           `env:"SEMANTIC_CACHE_MAX_PROMPTS" envDefault:"50"`
+	Timeout    time.Duration `env:"SEMANTIC_CACHE_TIMEOUT" envDefault:"5s"`
 }
 
 type RedisConfig struct {
@@ -24,13 +91,90 @@ type ServerConfig struct {
 	Port            string        `env:"SERVER_PORT" envDefault:"8080"`
 	Timeout         time.Duration `env:"SERVER_TIMEOUT" envDefault:"2m"`
 	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" envDefault:"10s"`
-	ThrottleLimit   int           `env:"SERVER_THROTTLE_LIMIT" envDefault:"50"`
 }
 
+type JobsConfig struct {
+	Workers   int           `env:"JOBS_WORKERS" envDefault:"2"`
+	QueueSize int           `env:"JOBS_QUEUE_SIZE" envDefault:"100"`
+	Timeout   time.Duration `env:"JOBS_TIMEOUT" envDefault:"30m"`
+	TTL       time.Duration `env:"JOBS_TTL" envDefault:"24h"`
+
+	// prefix of job ids, a restarted replica fails only the unfinished jobs of its own instance.
+	// Must be stable across restarts and unique among replicas, the hostname by default
+	Instance string `env:"JOBS_INSTANCE"`
+}
+
+// SessionsConfig bounds the history sent to the model, oldest turns are dropped first.
+// Sessions expire after TTL of inactivity
+type SessionsConfig struct {
+	TTL              time.Duration `env:"SESSIONS_TTL" envDefault:"1h"`
+	MaxHistoryTokens int           `env:"SESSIONS_MAX_HISTORY_TOKENS" envDefault:"4000"`
+}
+
+// StreamConfig controls SSE resumption: generation keeps running for ResumeGrace after
+// the last client disconnected and the emitted events are kept for Retention after it finished.
+// Heartbeat comments are sent when there were no events for HeartbeatInterval.
+// Streams and their connections last at most MaxDuration instead of SERVER_TIMEOUT
+type StreamConfig struct {
+	HeartbeatInterval time.Duration `env:"STREAM_HEARTBEAT_INTERVAL" envDefault:"15s"`
+	ResumeGrace       time.Duration `env:"STREAM_RESUME_GRACE" envDefault:"30s"`
+	Retention         time.Duration `env:"STREAM_RETENTION" envDefault:"2m"`
+	MaxDuration       time.Duration `env:"STREAM_MAX_DURATION" envDefault:"15m"`
+}
+
+// UploadConfig limits uploaded files to MaxSize bytes, both raw multipart parts and base64 in JSON.
+// Multipart parts above MaxMemory are spilled to temp files
+type UploadConfig struct {
+	MaxSize   int64 `env:"UPLOAD_MAX_SIZE" envDefault:"33554432"`
+	MaxMemory int64 `env:"UPLOAD_MAX_MEMORY" envDefault:"8388608"`
+}
+
+// QueueConfig limits running model calls
+type QueueConfig struct {
+	// the total llama.cpp N_PARALLEL of the backends
+	Concurrency int `env:"QUEUE_CONCURRENCY" envDefault:"4"`
+	MaxSize     int `env:"QUEUE_MAX_SIZE" envDefault:"100"`
+	// wait of interactive calls, jobs and batches wait within their own timeouts
+	Timeout time.Duration `env:"QUEUE_TIMEOUT" envDefault:"2m"`
+	// drawio, bpmn, svg and pdf renderings running at once
+	PreprocessConcurrency int `env:"QUEUE_PREPROCESS_CONCURRENCY" envDefault:"2"`
+}
+
+// BatchConfig limits batches, request bodies are limited by UPLOAD_MAX_SIZE as a whole.
+// Batch routes run for at most Timeout instead of SERVER_TIMEOUT
+type BatchConfig struct {
+	Concurrency int           `env:"BATCH_CONCURRENCY" envDefault:"2"`
+	MaxItems    int           `env:"BATCH_MAX_ITEMS" envDefault:"50"`
+	Timeout     time.Duration `env:"BATCH_TIMEOUT" envDefault:"30m"`
+}
+
+// OpenAIConfig describes the model backends
 type OpenAIConfig struct {
 	APIKey  string `env:"OPENAI_API_KEY"`
 	BaseURL string `env:"OPENAI_BASE_URL" envDefault:"http://localhost:8000/v1"`
 	Model   string `env:"OPENAI_MODEL" envDefault:"default"`
+
+	// "url" or "url|model" entries replacing BaseURL
+	Backends []string `env:"OPENAI_BACKENDS" envSeparator:","`
+	// model chains by file format or "text"/"vision" input, e.g. "txt:qwen|minicpm,vision:minicpm"
+	Routes map[string]string `env:"OPENAI_ROUTES" envSeparator:"," envKeyValSeparator:":"`
+
+	// backend health checks, ReadSlots routes by free llama.cpp slots
+	HealthInterval time.Duration `env:"OPENAI_HEALTH_INTERVAL" envDefault:"10s"`
+	HealthTimeout  time.Duration `env:"OPENAI_HEALTH_TIMEOUT" envDefault:"3s"`
+	ReadSlots      bool          `env:"OPENAI_READ_SLOTS" envDefault:"false"`
+
+	// a backend circuit opens after MaxFailures failures in a row for BreakerCooldown
+	MaxFailures     int           `env:"OPENAI_MAX_FAILURES" envDefault:"3"`
+	BreakerCooldown time.Duration `env:"OPENAI_BREAKER_COOLDOWN" envDefault:"30s"`
+
+	// CallTimeout bounds non-streamed calls only, streams are bounded by the first token and idle timeouts
+	CallTimeout       time.Duration `env:"OPENAI_CALL_TIMEOUT" envDefault:"2m"`
+	FirstTokenTimeout time.Duration `env:"OPENAI_FIRST_TOKEN_TIMEOUT" envDefault:"60s"`
+	StreamIdleTimeout time.Duration `env:"OPENAI_STREAM_IDLE_TIMEOUT" envDefault:"30s"`
+	// retries of unavailable backends, streams are retried only before the first token
+	Retries      int           `env:"OPENAI_RETRIES" envDefault:"2"`
+	RetryBackoff time.Duration `env:"OPENAI_RETRY_BACKOFF" envDefault:"250ms"`
 }
 
 func Load() (*Config, error) {
@@ -38,5 +182,57 @@ func Load() (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
+	if cfg.Jobs.Instance == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("JOBS_INSTANCE is not set and the hostname is unknown: %w", err)
+		}
+		cfg.Jobs.Instance = host
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// validate rejects values the services would fail on only at runtime
+func (c *Config) validate() error {
+	switch c.CacheBackend {
+	case CacheBackendRedis, CacheBackendDisk, CacheBackendMemory:
+	default:
+		return fmt.Errorf("CACHE_BACKEND must be %q, %q or %q, got %q",
+			CacheBackendRedis, CacheBackendDisk, CacheBackendMemory, c.CacheBackend)
+	}
+	if c.Stream.HeartbeatInterval <= 0 {
+		return fmt.Errorf("STREAM_HEARTBEAT_INTERVAL must be positive, got %s", c.Stream.HeartbeatInterval)
+	}
+	if c.OpenAI.HealthInterval <= 0 {
+		return fmt.Errorf("OPENAI_HEALTH_INTERVAL must be positive, got %s", c.OpenAI.HealthInterval)
+	}
+	if c.OpenAI.HealthTimeout <= 0 {
+		return fmt.Errorf("OPENAI_HEALTH_TIMEOUT must be positive, got %s", c.OpenAI.HealthTimeout)
+	}
+	if c.OpenAI.CallTimeout <= 0 || c.OpenAI.CallTimeout > c.Server.Timeout {
+		return fmt.Errorf("OPENAI_CALL_TIMEOUT must be positive and at most SERVER_TIMEOUT %s, got %s",
+			c.Server.Timeout, c.OpenAI.CallTimeout)
+	}
+	if c.OpenAI.FirstTokenTimeout <= 0 {
+		return fmt.Errorf("OPENAI_FIRST_TOKEN_TIMEOUT must be positive, got %s", c.OpenAI.FirstTokenTimeout)
+	}
+	if c.OpenAI.StreamIdleTimeout <= 0 {
+		return fmt.Errorf("OPENAI_STREAM_IDLE_TIMEOUT must be positive, got %s", c.OpenAI.StreamIdleTimeout)
+	}
+	if strings.Contains(c.Jobs.Instance, "_") {
+		return fmt.Errorf("JOBS_INSTANCE must not contain \"_\", got %q", c.Jobs.Instance)
+	}
+	if c.Stream.MaxDuration <= 0 {
+		return fmt.Errorf("STREAM_MAX_DURATION must be positive, got %s", c.Stream.MaxDuration)
+	}
+	if c.Batch.Timeout <= 0 {
+		return fmt.Errorf("BATCH_TIMEOUT must be positive, got %s", c.Batch.Timeout)
+	}
+	if c.CacheDisk.SweepInterval <= 0 {
+		return fmt.Errorf("CACHE_DISK_SWEEP_INTERVAL must be positive, got %s", c.CacheDisk.SweepInterval)
+	}
+	return nil
+}
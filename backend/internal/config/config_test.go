@@ -0,0 +1,66 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadValidate(t *testing.T) {
+	tests := map[string]struct {
+		env     map[string]string
+		wantErr string
+	}{
+		"defaults": {},
+		"unknown cache backend": {
+			env:     map[string]string{"CACHE_BACKEND": "etcd"},
+			wantErr: "CACHE_BACKEND",
+		},
+		"zero health interval": {
+			env:     map[string]string{"OPENAI_HEALTH_INTERVAL": "0s"},
+			wantErr: "OPENAI_HEALTH_INTERVAL",
+		},
+		"negative health timeout": {
+			env:     map[string]string{"OPENAI_HEALTH_TIMEOUT": "-1s"},
+			wantErr: "OPENAI_HEALTH_TIMEOUT",
+		},
+		"call timeout above server timeout": {
+			env:     map[string]string{"SERVER_TIMEOUT": "1m", "OPENAI_CALL_TIMEOUT": "2m"},
+			wantErr: "OPENAI_CALL_TIMEOUT",
+		},
+		"call timeout at server timeout": {
+			env: map[string]string{"SERVER_TIMEOUT": "5m", "OPENAI_CALL_TIMEOUT": "5m"},
+		},
+		"zero first token timeout": {
+			env:     map[string]string{"OPENAI_FIRST_TOKEN_TIMEOUT": "0s"},
+			wantErr: "OPENAI_FIRST_TOKEN_TIMEOUT",
+		},
+		"zero stream idle timeout": {
+			env:     map[string]string{"OPENAI_STREAM_IDLE_TIMEOUT": "0s"},
+			wantErr: "OPENAI_STREAM_IDLE_TIMEOUT",
+		},
+		"job instance with separator": {
+			env:     map[string]string{"JOBS_INSTANCE": "api_1"},
+			wantErr: "JOBS_INSTANCE",
+		},
+		"zero stream max duration": {
+			env:     map[string]string{"STREAM_MAX_DURATION": "0s"},
+			wantErr: "STREAM_MAX_DURATION",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("Load: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("Load error = %v, want it to name %s", err, tc.wantErr)
+			case err == nil && cfg.Jobs.Instance == "":
+				t.Fatal("JOBS_INSTANCE isn't defaulted to the hostname")
+			}
+		})
+	}
+}
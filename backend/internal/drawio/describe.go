@@ -0,0 +1,141 @@
+package drawio
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	lineBreakTags = regexp.MustCompile(`(?i)<\s*/?\s*(br|div|p|li|tr)\b[^>]*>`)
+	htmlTags      = regexp.MustCompile(`<[^>]*>`)
+	spaces        = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// cleanLabel turns draw.io html labels into plain text keeping line breaks as separators
+func cleanLabel(label string) string {
+	label = lineBreakTags.ReplaceAllString(label, "\n")
+	label = htmlTags.ReplaceAllString(label, "")
+	label = html.UnescapeString(label)
+
+	var lines []string
+	for _, line := range strings.Split(label, "\n") {
+		line = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " / ")
+}
+
+// Describe renders the diagram as a compact text description for the LLM prompt
+func (d *Diagram) Describe() string {
+	var b strings.Builder
+	for i, page := range d.Pages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		page.describe(&b)
+	}
+	return b.String()
+}
+
+func (p Page) describe(b *strings.Builder) {
+	names := make(map[string]string, len(p.Nodes)+len(p.Containers))
+	for _, n := range p.Containers {
+		names[n.ID] = n.displayName()
+	}
+	for _, n := range p.Nodes {
+		names[n.ID] = n.displayName()
+	}
+
+	fmt.Fprintf(b, "Page %q\n", p.Name)
+
+	if len(p.Containers) > 0 {
+		b.WriteString("Containers:\n")
+		for _, c := range p.Containers {
+			fmt.Fprintf(b, "- %s", names[c.ID])
+			if c.Shape != "" {
+				fmt.Fprintf(b, " (%s)", c.Shape)
+			}
+			if c.Parent != "" {
+				fmt.Fprintf(b, " inside %s", nameOf(names, c.Parent))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(p.Nodes) > 0 {
+		b.WriteString("Nodes:\n")
+		for _, n := range p.Nodes {
+			if n.Label == "" {
+				continue
+			}
+			fmt.Fprintf(b, "- %s", names[n.ID])
+			if n.Shape != "" {
+				fmt.Fprintf(b, " (%s)", n.Shape)
+			}
+			if n.Parent != "" {
+				fmt.Fprintf(b, " inside %s", nameOf(names, n.Parent))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(p.Edges) > 0 {
+		b.WriteString("Edges:\n")
+		for _, e := range p.Edges {
+			if e.Source == "" && e.Target == "" && e.Label == "" {
+				continue
+			}
+			fmt.Fprintf(b, "- %s -> %s", nameOf(names, e.Source), nameOf(names, e.Target))
+			if e.Label != "" {
+				fmt.Fprintf(b, ": %s", e.Label)
+			}
+			b.WriteString("\n")
+		}
+	}
+}
+
+func (n Node) displayName() string {
+	if n.Label != "" {
+		return fmt.Sprintf("%q", n.Label)
+	}
+	if n.Shape != "" {
+		return fmt.Sprintf("<%s #%s>", n.Shape, n.ID)
+	}
+	return fmt.Sprintf("#%s", n.ID)
+}
+
+func nameOf(names map[string]string, id string) string {
+	if id == "" {
+		return "(unconnected)"
+	}
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("#%s", id)
+}
+
+// Empty reports whether the diagram has no labeled content worth describing
+func (d *Diagram) Empty() bool {
+	for _, p := range d.Pages {
+		for _, n := range p.Nodes {
+			if n.Label != "" {
+				return false
+			}
+		}
+		for _, n := range p.Containers {
+			if n.Label != "" {
+				return false
+			}
+		}
+		for _, e := range p.Edges {
+			if e.Label != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,332 @@
+package drawio
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ErrTooLarge is returned when compressed pages inflate above the size limit
+var ErrTooLarge = errors.New("inflated drawio pages exceed the size limit")
+
+// Diagram is a parsed draw.io file with all of its pages
+type Diagram struct {
+	Pages []Page
+}
+
+// Page is a single draw.io page (a <diagram> element)
+type Page struct {
+	Name       string
+	Nodes      []Node
+	Edges      []Edge
+	Containers []Node
+}
+
+// Node is a vertex cell. Containers and swimlanes are nodes too
+type Node struct {
+	ID        string
+	Label     string
+	Shape     string
+	Parent    string
+	Container bool
+}
+
+// Edge is a connection between two cells
+type Edge struct {
+	ID     string
+	Label  string
+	Source string
+	Target string
+}
+
+type xmlFile struct {
+	XMLName  xml.Name
+	Diagrams []xmlDiagram `xml:"diagram"`
+	Root     *xmlRoot     `xml:"root"`
+}
+
+type xmlDiagram struct {
+	Name  string         `xml:"name,attr"`
+	Model *xmlGraphModel `xml:"mxGraphModel"`
+	Data  string         `xml:",chardata"`
+}
+
+type xmlGraphModel struct {
+	Root xmlRoot `xml:"root"`
+}
+
+type xmlRoot struct {
+	Cells []xmlCell `xml:",any"`
+}
+
+// xmlCell covers both plain <mxCell> elements and <object>/<UserObject>
+// wrappers, which keep custom attributes outside and the geometry in a nested <mxCell>
+type xmlCell struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Cell    *xmlCell   `xml:"mxCell"`
+}
+
+func (c xmlCell) attr(name string) string {
+	for _, a := range c.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	if c.Cell != nil {
+		return c.Cell.attr(name)
+	}
+	return ""
+}
+
+// Parse reads an mxfile or a bare mxGraphModel document.
+// Compressed pages (deflate + base64) are inflated transparently, at most maxSize bytes of all pages together
+func Parse(data []byte, maxSize int64) (*Diagram, error) {
+	var file xmlFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid drawio xml: %w", err)
+	}
+
+	switch file.XMLName.Local {
+	case "mxfile":
+	case "mxGraphModel":
+		if file.Root == nil {
+			return nil, fmt.Errorf("mxGraphModel has no root")
+		}
+		return &Diagram{Pages: []Page{buildPage("", file.Root.Cells)}}, nil
+	default:
+		return nil, fmt.Errorf("unexpected root element <%s>", file.XMLName.Local)
+	}
+
+	diagram := &Diagram{}
+	for i, d := range file.Diagrams {
+		model := d.Model
+		if model == nil {
+			decoded, inflated, err := inflate(d.Data, maxSize)
+			if err != nil {
+				return nil, fmt.Errorf("page %d: %w", i+1, err)
+			}
+			maxSize -= inflated
+			model = &xmlGraphModel{}
+			if err := xml.Unmarshal(decoded, model); err != nil {
+				return nil, fmt.Errorf("page %d: invalid compressed model: %w", i+1, err)
+			}
+		}
+
+		name := d.Name
+		if name == "" {
+			name = fmt.Sprintf("Page-%d", i+1)
+		}
+		diagram.Pages = append(diagram.Pages, buildPage(name, model.Root.Cells))
+	}
+
+	if len(diagram.Pages) == 0 {
+		return nil, fmt.Errorf("mxfile has no diagrams")
+	}
+	return diagram, nil
+}
+
+// inflate decodes the draw.io compressed payload: base64 -> raw deflate -> URI encoding.
+// size is the inflated size counted against maxSize, payloads inflating above it fail with ErrTooLarge
+func inflate(payload string, maxSize int64) (model []byte, size int64, err error) {
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		return nil, 0, fmt.Errorf("empty diagram payload")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode base64: %w", err)
+	}
+
+	inflated, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(raw)), max(maxSize, 0)+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to inflate: %w", err)
+	}
+	size = int64(len(inflated))
+	if size > maxSize {
+		return nil, 0, ErrTooLarge
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(inflated), []byte("<")) {
+		return inflated, size, nil
+	}
+
+	unescaped, err := url.PathUnescape(string(inflated))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to unescape: %w", err)
+	}
+	return []byte(unescaped), size, nil
+}
+
+func buildPage(name string, cells []xmlCell) Page {
+	page := Page{Name: name}
+
+	// cells without parent are the model root and its direct children are layers
+	var rootID string
+	for _, c := range cells {
+		if c.attr("parent") == "" {
+			rootID = c.attr("id")
+			break
+		}
+	}
+
+	layers := make(map[string]bool)
+	children := make(map[string]int)
+	edgeLabels := make(map[string][]string)
+
+	for _, c := range cells {
+		parent := c.attr("parent")
+		if parent == "" {
+			continue
+		}
+		if parent == rootID {
+			layers[c.attr("id")] = true
+			continue
+		}
+		if c.attr("vertex") == "1" {
+			children[parent]++
+		}
+	}
+
+	edges := make(map[string]bool)
+	for _, c := range cells {
+		if c.attr("edge") == "1" {
+			edges[c.attr("id")] = true
+		}
+	}
+
+	for _, c := range cells {
+		id := c.attr("id")
+		if c.attr("parent") == "" || layers[id] {
+			continue
+		}
+
+		label := cellLabel(c)
+		parent := c.attr("parent")
+		if layers[parent] {
+			parent = ""
+		}
+
+		switch {
+		case c.attr("edge") == "1":
+			page.Edges = append(page.Edges, Edge{
+				ID:     id,
+				Label:  label,
+				Source: c.attr("source"),
+				Target: c.attr("target"),
+			})
+		case edges[parent]:
+			// edge labels are stored as vertices attached to the edge
+			if label != "" {
+				edgeLabels[parent] = append(edgeLabels[parent], label)
+			}
+		case c.attr("vertex") == "1":
+			style := parseStyle(c.attr("style"))
+			node := Node{
+				ID:     id,
+				Label:  label,
+				Shape:  shapeName(style),
+				Parent: parent,
+			}
+			node.Container = children[id] > 0 || hasKey(style, "swimlane")
+			if node.Container {
+				page.Containers = append(page.Containers, node)
+			} else {
+				page.Nodes = append(page.Nodes, node)
+			}
+		}
+	}
+
+	page.flattenGroups()
+
+	for i, e := range page.Edges {
+		if extra := edgeLabels[e.ID]; len(extra) > 0 {
+			page.Edges[i].Label = strings.TrimSpace(e.Label + " " + strings.Join(extra, " "))
+		}
+	}
+
+	return page
+}
+
+// flattenGroups drops unlabeled groups and moves their children to the closest labeled container
+func (p *Page) flattenGroups() {
+	groups := make(map[string]string)
+	containers := p.Containers[:0]
+	for _, c := range p.Containers {
+		if c.Label == "" {
+			groups[c.ID] = c.Parent
+			continue
+		}
+		containers = append(containers, c)
+	}
+	p.Containers = containers
+
+	resolve := func(parent string) string {
+		for i := 0; i < len(groups); i++ {
+			next, ok := groups[parent]
+			if !ok {
+				break
+			}
+			parent = next
+		}
+		return parent
+	}
+
+	for i := range p.Containers {
+		p.Containers[i].Parent = resolve(p.Containers[i].Parent)
+	}
+	for i := range p.Nodes {
+		p.Nodes[i].Parent = resolve(p.Nodes[i].Parent)
+	}
+}
+
+func cellLabel(c xmlCell) string {
+	label := c.attr("label")
+	if label == "" {
+		label = c.attr("value")
+	}
+
+	// placeholders="1" lets labels reference the object's own attributes as %name%
+	if c.attr("placeholders") == "1" {
+		for _, a := range c.Attrs {
+			label = strings.ReplaceAll(label, "%"+a.Name.Local+"%", a.Value)
+		}
+	}
+	return cleanLabel(label)
+}
+
+func parseStyle(style string) map[string]string {
+	result := make(map[string]string)
+	for _, part := range strings.Split(style, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		result[key] = value
+	}
+	return result
+}
+
+func hasKey(style map[string]string, key string) bool {
+	_, ok := style[key]
+	return ok
+}
+
+func shapeName(style map[string]string) string {
+	if shape := style["shape"]; shape != "" && shape != "ext" {
+		return strings.TrimPrefix(shape, "mxgraph.")
+	}
+	for _, known := range []string{"swimlane", "ellipse", "rhombus", "triangle", "text", "image"} {
+		if hasKey(style, known) {
+			return known
+		}
+	}
+	return ""
+}
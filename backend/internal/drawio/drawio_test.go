@@ -0,0 +1,232 @@
+package drawio
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testMaxSize = 1 << 20
+
+// compress encodes a model the way draw.io does: URI encoding -> raw deflate -> base64
+func compress(t *testing.T, model string) string {
+	t.Helper()
+	return base64.StdEncoding.EncodeToString(deflate(t, url.PathEscape(model)))
+}
+
+func deflate(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func model(cells string) string {
+	return `<mxGraphModel><root><mxCell id="0"/><mxCell id="1" parent="0"/>` + cells + `</root></mxGraphModel>`
+}
+
+func TestParse(t *testing.T) {
+	compressed := compress(t, model(`
+		<mxCell id="a" value="Client" vertex="1" parent="1" style="ellipse;whiteSpace=wrap"/>
+		<mxCell id="b" value="&lt;b&gt;API&lt;/b&gt;&lt;br&gt;gateway" vertex="1" parent="1" style="shape=mxgraph.aws4.api_gateway"/>
+		<mxCell id="e" value="HTTPS" edge="1" parent="1" source="a" target="b"/>`))
+
+	tests := map[string]struct {
+		data  string
+		pages []Page
+	}{
+		"compressed multi-page mxfile": {
+			data: `<mxfile><diagram name="Overview">` + compressed + `</diagram><diagram>` +
+				model(`<mxCell id="db" value="Database" vertex="1" parent="1" style="shape=cylinder3"/>`) + `</diagram></mxfile>`,
+			pages: []Page{
+				{
+					Name: "Overview",
+					Nodes: []Node{
+						{ID: "a", Label: "Client", Shape: "ellipse"},
+						{ID: "b", Label: "API / gateway", Shape: "aws4.api_gateway"},
+					},
+					Edges: []Edge{{ID: "e", Label: "HTTPS", Source: "a", Target: "b"}},
+				},
+				{
+					Name:  "Page-2",
+					Nodes: []Node{{ID: "db", Label: "Database", Shape: "cylinder3"}},
+				},
+			},
+		},
+		"bare mxGraphModel": {
+			data: model(`
+				<mxCell id="a" value="A" vertex="1" parent="1"/>
+				<mxCell id="b" value="B" vertex="1" parent="1" style="rhombus"/>
+				<mxCell id="e" edge="1" parent="1" source="a" target="b"/>`),
+			pages: []Page{{
+				Nodes: []Node{{ID: "a", Label: "A"}, {ID: "b", Label: "B", Shape: "rhombus"}},
+				Edges: []Edge{{ID: "e", Source: "a", Target: "b"}},
+			}},
+		},
+		"object with placeholders": {
+			data: model(`
+				<object id="svc" label="%name% (%kind%)" name="Billing" kind="service" placeholders="1">
+					<mxCell vertex="1" parent="1" style="rounded=1"/>
+				</object>
+				<UserObject id="raw" label="%name%" name="ignored">
+					<mxCell vertex="1" parent="1"/>
+				</UserObject>`),
+			pages: []Page{{
+				Nodes: []Node{{ID: "svc", Label: "Billing (service)"}, {ID: "raw", Label: "%name%"}},
+			}},
+		},
+		"edge with child label": {
+			data: model(`
+				<mxCell id="a" value="A" vertex="1" parent="1"/>
+				<mxCell id="b" value="B" vertex="1" parent="1"/>
+				<mxCell id="e" value="sync" edge="1" parent="1" source="a" target="b"/>
+				<mxCell id="l1" value="calls" vertex="1" connectable="0" parent="e" style="edgeLabel"/>
+				<mxCell id="l2" value="" vertex="1" connectable="0" parent="e" style="edgeLabel"/>`),
+			pages: []Page{{
+				Nodes: []Node{{ID: "a", Label: "A"}, {ID: "b", Label: "B"}},
+				Edges: []Edge{{ID: "e", Label: "sync calls", Source: "a", Target: "b"}},
+			}},
+		},
+		"nested unlabeled groups": {
+			data: model(`
+				<mxCell id="vpc" value="VPC" vertex="1" parent="1" style="swimlane"/>
+				<mxCell id="g1" value="" vertex="1" parent="vpc" style="group"/>
+				<mxCell id="g2" value="" vertex="1" parent="g1" style="group"/>
+				<mxCell id="svc" value="Service" vertex="1" parent="g2"/>
+				<mxCell id="g3" value="" vertex="1" parent="1" style="group"/>
+				<mxCell id="free" value="Free" vertex="1" parent="g3"/>`),
+			pages: []Page{{
+				Nodes: []Node{
+					{ID: "svc", Label: "Service", Parent: "vpc"},
+					{ID: "free", Label: "Free"},
+				},
+				Containers: []Node{{ID: "vpc", Label: "VPC", Shape: "swimlane", Container: true}},
+			}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			diagram, err := Parse([]byte(tc.data), testMaxSize)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(diagram.Pages) != len(tc.pages) {
+				t.Fatalf("got %d pages, want %d", len(diagram.Pages), len(tc.pages))
+			}
+			for i, want := range tc.pages {
+				got := diagram.Pages[i]
+				if got.Name != want.Name {
+					t.Errorf("page %d name = %q, want %q", i, got.Name, want.Name)
+				}
+				if !slices.Equal(got.Nodes, want.Nodes) {
+					t.Errorf("page %d nodes = %+v, want %+v", i, got.Nodes, want.Nodes)
+				}
+				if !slices.Equal(got.Edges, want.Edges) {
+					t.Errorf("page %d edges = %+v, want %+v", i, got.Edges, want.Edges)
+				}
+				if !slices.Equal(got.Containers, want.Containers) {
+					t.Errorf("page %d containers = %+v, want %+v", i, got.Containers, want.Containers)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"broken xml":       `<mxfile><diagram>`,
+		"foreign root":     `<svg/>`,
+		"no diagrams":      `<mxfile></mxfile>`,
+		"no root":          `<mxGraphModel/>`,
+		"invalid base64":   `<mxfile><diagram>not base64!</diagram></mxfile>`,
+		"empty payload":    `<mxfile><diagram> </diagram></mxfile>`,
+		"invalid deflate":  `<mxfile><diagram>` + base64.StdEncoding.EncodeToString([]byte("plain")) + `</diagram></mxfile>`,
+		"invalid compress": `<mxfile><diagram>` + base64.StdEncoding.EncodeToString(deflate(t, "%zz")) + `</diagram></mxfile>`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data), testMaxSize); err == nil {
+				t.Fatal("Parse = nil error")
+			}
+		})
+	}
+}
+
+func TestParseTooLarge(t *testing.T) {
+	t.Run("deflate bomb", func(t *testing.T) {
+		bomb := base64.StdEncoding.EncodeToString(deflate(t, strings.Repeat(" ", 8*testMaxSize)))
+		data := `<mxfile><diagram>` + bomb + `</diagram></mxfile>`
+		if _, err := Parse([]byte(data), testMaxSize); !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("Parse error = %v, want ErrTooLarge", err)
+		}
+	})
+
+	// a URI encoded page shrinks when unescaped, the budget counts what was inflated
+	t.Run("pages share the inflated budget", func(t *testing.T) {
+		page := compress(t, model(`<mxCell id="a" value="`+strings.Repeat("я", 100)+`" vertex="1" parent="1"/>`))
+		_, size, err := inflate(page, testMaxSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := []byte(fmt.Sprintf(`<mxfile><diagram>%s</diagram><diagram>%s</diagram></mxfile>`, page, page))
+
+		if _, err := Parse(data, 2*size); err != nil {
+			t.Fatalf("Parse within the budget: %v", err)
+		}
+		if _, err := Parse(data, 2*size-1); !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("Parse error = %v, want ErrTooLarge", err)
+		}
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	data := model(`
+		<mxCell id="vpc" value="VPC" vertex="1" parent="1" style="swimlane"/>
+		<mxCell id="api" value="API" vertex="1" parent="vpc"/>
+		<mxCell id="db" value="" vertex="1" parent="vpc" style="shape=cylinder3"/>
+		<mxCell id="e" value="SQL" edge="1" parent="1" source="api" target="db"/>
+		<mxCell id="dangling" edge="1" parent="1" source="api"/>`)
+	diagram, err := Parse([]byte(data), testMaxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Page ""
+Containers:
+- "VPC" (swimlane)
+Nodes:
+- "API" inside "VPC"
+Edges:
+- "API" -> <cylinder3 #db>: SQL
+- "API" -> (unconnected)
+`
+	if got := diagram.Describe(); got != want {
+		t.Errorf("Describe =\n%s\nwant\n%s", got, want)
+	}
+	if diagram.Empty() {
+		t.Error("Empty = true for a labeled diagram")
+	}
+
+	unlabeled, err := Parse([]byte(model(`<mxCell id="a" vertex="1" parent="1"/>`)), testMaxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unlabeled.Empty() {
+		t.Error("Empty = false for a diagram without labels")
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-chi/chi/v5"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
+)
+
+type cacheAdminService interface {
+	Lookup(ctx context.Context, hash string) ([]models.CacheEntry, error)
+	Delete(ctx context.Context, hash string) (int, error)
+	Purge(ctx context.Context, req *models.CachePurgeRequest) (int, error)
+	Stats(ctx context.Context) (*models.CacheStats, error)
+}
+
+type CacheAdminHandler struct {
+	service cacheAdminService
+}
+
+func NewCacheAdminHandler(service cacheAdminService) *CacheAdminHandler {
+	return &CacheAdminHandler{service: service}
+}
+
+// RequireToken rejects requests without "Authorization: Bearer <token>"
+func RequireToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				http.Error(w, "invalid admin token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Stats godoc
+// @Summary Cache statistics
+// @Description Entries and bytes of cache entries of every kind per cache tier, answer hits and misses since the backend start
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Success 200 {object} models.CacheStats
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/cache/stats [get]
+func (h *CacheAdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.Stats(r.Context())
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// Lookup godoc
+// @Summary Look up cache entries
+// @Description Get cache entries of every kind of the current cache version by file content hash (sha256 of the decoded file) or by <file hash>:<request hash>
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param hash path string true "File hash or <file hash>:<request hash>"
+// @Success 200 {array} models.CacheEntry
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/cache/entries/{hash} [get]
+func (h *CacheAdminHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.service.Lookup(r.Context(), chi.URLParam(r, "hash"))
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// Delete godoc
+// @Summary Delete cache entries
+// @Description Delete cache entries of every kind of the current cache version by file content hash or by <file hash>:<request hash>.
+// @Description With the redis backend the memory tier of other replicas keeps its copies until CACHE_MEMORY_TTL
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param hash path string true "File hash or <file hash>:<request hash>"
+// @Success 200 {object} models.CachePurgeResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/cache/entries/{hash} [delete]
+func (h *CacheAdminHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.service.Delete(r.Context(), chi.URLParam(r, "hash"))
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.CachePurgeResponse{Deleted: deleted})
+}
+
+// Purge godoc
+// @Summary Purge cache entries
+// @Description Delete cache entries by key prefix, kind, model, prompt version or cache version. All given filters must match.
+// @Description Keys are listed by a scan per kind, kind, cache version and prefix filters narrow the scan
+// @Description With the redis backend the memory tier of other replicas keeps its copies until CACHE_MEMORY_TTL
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param request body models.CachePurgeRequest true "Purge filters"
+// @Success 200 {object} models.CachePurgeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/cache/purge [post]
+func (h *CacheAdminHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	var req models.CachePurgeRequest
+	if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.service.Purge(r.Context(), &req)
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.CachePurgeResponse{Deleted: deleted})
+}
+
+func writeCacheAdminError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrCacheEntryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("service error: %s", err), http.StatusInternalServerError)
+}
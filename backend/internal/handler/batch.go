@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
+)
+
+type batchService interface {
+	Run(ctx context.Context, req *models.BatchExplainRequest, emit func(models.BatchItemResult)) error
+}
+
+type BatchHandler struct {
+	service batchService
+	upload  config.UploadConfig
+}
+
+func NewBatchHandler(service batchService, upload config.UploadConfig) *BatchHandler {
+	return &BatchHandler{
+		service: service,
+		upload:  upload,
+	}
+}
+
+// Explain godoc
+// @Summary Explain many diagrams
+// @Description Explain a batch of images + prompts. Items are processed concurrently and results are returned in request order with per-item errors.
+// @Description Items left when the batch runs into BATCH_TIMEOUT are returned with an error next to the finished ones.
+// @Tags explain
+// @Accept json
+// @Produce json
+// @Param request body models.BatchExplainRequest true "Batch explain request"
+// @Success 200 {object} models.BatchExplainResponse
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /explain/batch [post]
+func (h *BatchHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBatchRequest(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	resp := models.BatchExplainResponse{
+		Items: make([]models.BatchItemResult, len(req.Items)),
+	}
+	err := h.service.Run(r.Context(), req, func(item models.BatchItemResult) {
+		resp.Items[item.Index] = item
+		if item.Error != "" {
+			resp.Failed++
+		}
+	})
+	// every item is emitted when the batch stops early, the finished ones are kept
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		writeBatchError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ExplainStream godoc
+// @Summary Stream batch explanation
+// @Description Explain a batch of images + prompts and emit each item as NDJSON line as soon as it finishes.
+// @Description Items left when the batch is cancelled or runs into BATCH_TIMEOUT are emitted with an error.
+// @Tags explain
+// @Accept json
+// @Produce application/x-ndjson
+// @Param request body models.BatchExplainRequest true "Batch explain request"
+// @Success 200 {object} models.BatchItemResult "Stream of items (NDJSON)"
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /explain/batch/stream [post]
+func (h *BatchHandler) ExplainStream(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBatchRequest(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	flusher := http.NewResponseController(w)
+	started := false
+
+	err := h.service.Run(r.Context(), req, func(item models.BatchItemResult) {
+		data, err := sonic.Marshal(item)
+		if err != nil {
+			data, _ = sonic.Marshal(models.BatchItemResult{
+				Index:    item.Index,
+				FileName: item.FileName,
+				Error:    fmt.Sprintf("marshal error %v", err),
+			})
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Cache-Control", "no-cache")
+			started = true
+		}
+		w.Write(append(data, '\n'))
+		flusher.Flush()
+	})
+	if err != nil && !started {
+		writeBatchError(w, err)
+	}
+}
+
+func decodeBatchRequest(w http.ResponseWriter, r *http.Request, upload config.UploadConfig) (*models.BatchExplainRequest, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, upload.MaxSize)
+
+	var req models.BatchExplainRequest
+	if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, fmt.Sprintf("invalid JSON: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+func writeBatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrBatchTooLarge) {
+		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("service error: %s", err), http.StatusInternalServerError)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// timedOutBatch finishes the first item and emits the rest with the timeout, as the service does
+type timedOutBatch struct{}
+
+func (timedOutBatch) Run(_ context.Context, req *models.BatchExplainRequest, emit func(models.BatchItemResult)) error {
+	emit(models.BatchItemResult{Index: 0, Result: &models.ExplainResponse{Explanation: "done"}})
+	for i := 1; i < len(req.Items); i++ {
+		emit(models.BatchItemResult{Index: i, Error: "service error: " + context.DeadlineExceeded.Error()})
+	}
+	return context.DeadlineExceeded
+}
+
+func TestBatchExplainTimeoutKeepsFinishedItems(t *testing.T) {
+	h := NewBatchHandler(timedOutBatch{}, config.UploadConfig{MaxSize: 1 << 20})
+	body := `{"items":[{"file_name":"a.txt","file_base64":"QQ=="},{"file_name":"b.txt","file_base64":"Qg=="}]}`
+	r := httptest.NewRequest(http.MethodPost, "/explain/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.Explain(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var resp models.BatchExplainResponse
+	if err := sonic.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].Result == nil || resp.Items[1].Error == "" || resp.Failed != 1 {
+		t.Fatalf("response = %+v, want the finished item and a failed one", resp)
+	}
+}
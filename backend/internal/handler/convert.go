@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
+)
+
+type convertService interface {
+	Convert(ctx context.Context, req *models.ConvertRequest) (*models.ConvertResponse, error)
+}
+
+type ConvertHandler struct {
+	service convertService
+	upload  config.UploadConfig
+}
+
+func NewConvertHandler(service convertService, upload config.UploadConfig) *ConvertHandler {
+	return &ConvertHandler{
+		service: service,
+		upload:  upload,
+	}
+}
+
+// Convert godoc
+// @Summary Convert diagram
+// @Description Convert diagram to Mermaid or PlantUML source. Generated code is validated and the model is asked to fix syntax errors. File is sent as base64 string in JSON or as a raw "file" part of multipart/form-data.
+// @Tags convert
+// @Accept json,mpfd
+// @Produce json
+// @Param request body models.ConvertRequest true "Convert request"
+// @Success 200 {object} models.ConvertResponse
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Failure 504 {object} map[string]string
+// @Router /convert [post]
+func (h *ConvertHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeConvertRequest(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.Convert(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidConversion) {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -2,11 +2,16 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/admission"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/llm"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
 )
 
 type explainService interface {
@@ -16,40 +21,42 @@ type explainService interface {
 
 type ExplainHandler struct {
 	service explainService
+	streams streamHub
+	upload  config.UploadConfig
 }
 
-func NewExplainHandler(service explainService) *ExplainHandler {
+func NewExplainHandler(service explainService, streams streamHub, upload config.UploadConfig) *ExplainHandler {
 	return &ExplainHandler{
 		service: service,
+		streams: streams,
+		upload:  upload,
 	}
 }
 
 // Explain godoc
 // @Summary Explain diagram image
-// @Description Explain architecture from image + prompt. Image is sent as base64 string in JSON.
+// @Description Explain architecture from image + prompt. Image is sent as base64 string in JSON or as a raw "file" part of multipart/form-data.
 // @Tags explain
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param request body models.ExplainRequest true "Explain request"
 // @Success 200 {object} models.ExplainResponse
 // @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Failure 504 {object} map[string]string
 // @Router /explain [post]
 func (h *ExplainHandler) Explain(w http.ResponseWriter, r *http.Request) {
-	var req models.ExplainRequest
-	if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("invalid JSON: %s", err), http.StatusBadRequest)
+	req, ok := decodeExplainRequest(w, r, h.upload)
+	if !ok {
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
-		return
-	}
-
-	resp, err := h.service.Send(r.Context(), &req)
+	resp, err := h.service.Send(r.Context(), req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("service error: %s", err), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -63,60 +70,52 @@ func (h *ExplainHandler) Explain(w http.ResponseWriter, r *http.Request) {
 
 // ExplainStream godoc
 // @Summary Stream explanation
-// @Description Stream explanation tokens from image + prompt. Image is sent as base64 string in JSON.
+// @Description Stream explanation tokens from image + prompt. Image is sent as base64 string in JSON or as a raw "file" part of multipart/form-data. Besides message events the stream has queued, meta, preprocessing, preprocessed, generating and usage events and heartbeat comments. Queued events with a position are sent while the model call waits in the queue. Events have ids, a client reconnecting with Last-Event-ID header gets the missed events and the rest of the stream, the body is ignored then.
 // @Tags explain
-// @Accept json
+// @Accept json,mpfd
 // @Produce text/event-stream
 // @Param request body models.ExplainRequest true "Explain request"
+// @Param Last-Event-ID header string false "Last received event id to resume the stream"
 // @Success 200 {object} models.StreamChunk "Stream of tokens (SSE)"
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /explain/stream [post]
 func (h *ExplainHandler) ExplainStream(w http.ResponseWriter, r *http.Request) {
-	var req models.ExplainRequest
-	if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("invalid JSON: %s", err), http.StatusBadRequest)
+	if resumeStream(w, r, h.streams) {
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
+	req, ok := decodeExplainRequest(w, r, h.upload)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-
-	flusher := http.NewResponseController(w)
-
-	stream, err := h.service.SendStream(r.Context(), &req)
+	events, err := h.streams.Start(r.Context(), func(ctx context.Context) (<-chan models.StreamChunk, error) {
+		return h.service.SendStream(ctx, req)
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
-	for chunk := range stream {
-		if chunk.Err != nil {
-			fmt.Fprintf(w, "event: error\ndata: %v\n\n", chunk.Err)
-			flusher.Flush()
-			return
-		}
-
-		data, err := sonic.Marshal(chunk)
-		if err != nil {
-			fmt.Fprintf(w, "event: error\ndata: marshal error %v\n\n", err)
-			flusher.Flush()
-			return
-		}
-
-		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
-		flusher.Flush()
+	writeStream(w, events, h.streams.Heartbeat())
+}
 
-		if chunk.Done {
-			fmt.Fprintf(w, "event: done\ndata: {}\n\n")
-			flusher.Flush()
-			return
-		}
+// writeServiceError maps unsupported file formats to 400 and model backend errors:
+// failed calls to 502, unavailable backends or a full queue to 503 and timeouts to 504
+func writeServiceError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrUnsupportedFormat):
+		status = http.StatusBadRequest
+	case errors.Is(err, llm.ErrUnavailable), errors.Is(err, admission.ErrQueueFull), errors.Is(err, admission.ErrQueueTimeout):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, llm.ErrTimeout):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, llm.ErrBadGateway):
+		status = http.StatusBadGateway
 	}
+	http.Error(w, fmt.Sprintf("service error: %s", err), status)
 }
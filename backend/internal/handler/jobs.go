@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-chi/chi/v5"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
+)
+
+type jobService interface {
+	Submit(ctx context.Context, req *models.ExplainRequest) (*models.Job, error)
+	Get(ctx context.Context, id string) (*models.Job, error)
+	Cancel(ctx context.Context, id string) (*models.Job, error)
+}
+
+type JobHandler struct {
+	service jobService
+	upload  config.UploadConfig
+}
+
+func NewJobHandler(service jobService, upload config.UploadConfig) *JobHandler {
+	return &JobHandler{
+		service: service,
+		upload:  upload,
+	}
+}
+
+// Create godoc
+// @Summary Create explanation job
+// @Description Queue explanation of image + prompt and return job ID immediately. Image is sent as base64 string in JSON or as a raw "file" part of multipart/form-data.
+// @Tags jobs
+// @Accept json,mpfd
+// @Produce json
+// @Param request body models.ExplainRequest true "Explain request"
+// @Success 202 {object} models.JobCreateResponse
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /jobs [post]
+func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeExplainRequest(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	job, err := h.service.Submit(r.Context(), req)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, models.JobCreateResponse{ID: job.ID, Status: job.Status})
+}
+
+// Get godoc
+// @Summary Get explanation job
+// @Description Get job status, progress, result and error
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs/{id} [get]
+func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// Cancel godoc
+// @Summary Cancel explanation job
+// @Description Cancel queued or running job. Finished jobs are returned unchanged
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs/{id} [delete]
+func (h *JobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.Cancel(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrUnsupportedFormat):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrJobQueueFull):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, fmt.Sprintf("service error: %s", err), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := sonic.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// uploadEnvelopeSlack is the room left in upload bodies for fields besides the file
+const uploadEnvelopeSlack = 1 << 20
+
+type validator interface {
+	Validate() error
+}
+
+// decodeExplainRequest reads either a JSON body with base64 file or a multipart/form-data
+// upload with a raw "file" part. Writes an error response and returns false on failure
+func decodeExplainRequest(w http.ResponseWriter, r *http.Request, upload config.UploadConfig) (*models.ExplainRequest, bool) {
+	req := &models.ExplainRequest{}
+	return req, decodeRequest(w, r, upload, req, req, nil)
+}
+
+// decodeConvertRequest is decodeExplainRequest with an extra "target" form field
+func decodeConvertRequest(w http.ResponseWriter, r *http.Request, upload config.UploadConfig) (*models.ConvertRequest, bool) {
+	req := &models.ConvertRequest{}
+	return req, decodeRequest(w, r, upload, req, &req.ExplainRequest, func(r *http.Request) {
+		req.Target = r.FormValue("target")
+	})
+}
+
+// decodeRequest decodes JSON body into v or multipart form into explain, which is embedded into v.
+// formFields reads fields of v which are not part of the explain request
+func decodeRequest(w http.ResponseWriter, r *http.Request, upload config.UploadConfig, v validator, explain *models.ExplainRequest, formFields func(r *http.Request)) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	multipart := mediaType == "multipart/form-data"
+	r.Body = http.MaxBytesReader(w, r.Body, uploadBodyLimit(upload, multipart))
+
+	var err error
+	if multipart {
+		err = decodeMultipart(r, upload, explain)
+		if err == nil && formFields != nil {
+			formFields(r)
+		}
+	} else {
+		if err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(v); err != nil {
+			err = fmt.Errorf("invalid JSON: %w", err)
+		} else if size, _ := io.Copy(io.Discard, io.LimitReader(explain.FileReader(), upload.MaxSize+1)); size > upload.MaxSize {
+			err = &http.MaxBytesError{Limit: upload.MaxSize}
+		}
+	}
+
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("request body is too large, files are limited to %d bytes", upload.MaxSize), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	if err := v.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// uploadBodyLimit lets files of up to MaxSize through in both encodings, base64 in JSON
+// is 4/3 of the file. The slack leaves room for the prompt and other fields
+func uploadBodyLimit(upload config.UploadConfig, multipart bool) int64 {
+	if multipart {
+		return upload.MaxSize + uploadEnvelopeSlack
+	}
+	return int64(base64.StdEncoding.EncodedLen(int(upload.MaxSize))) + uploadEnvelopeSlack
+}
+
+// decodeMultipart keeps parts up to MaxMemory in memory and spills bigger ones to temp files.
+// The file is streamed into base64, which is what the model gets, instead of buffering raw bytes.
+// Services decode it back only for formats they parse
+func decodeMultipart(r *http.Request, upload config.UploadConfig, req *models.ExplainRequest) error {
+	if err := r.ParseMultipartForm(upload.MaxMemory); err != nil {
+		return fmt.Errorf("invalid multipart form: %w", err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return fmt.Errorf("invalid multipart form: file part: %w", err)
+	}
+	defer file.Close()
+
+	var encoded strings.Builder
+	encoded.Grow(base64.StdEncoding.EncodedLen(int(min(header.Size, upload.MaxSize))))
+	encoder := base64.NewEncoder(base64.StdEncoding, &encoded)
+	size, err := io.Copy(encoder, io.LimitReader(file, upload.MaxSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if size > upload.MaxSize {
+		return &http.MaxBytesError{Limit: upload.MaxSize}
+	}
+	encoder.Close()
+
+	*req = models.ExplainRequest{
+		Prompt:     r.FormValue("prompt"),
+		FileName:   r.FormValue("file_name"),
+		FileFormat: r.FormValue("file_format"),
+		Output:     r.FormValue("output"),
+		FileBase64: encoded.String(),
+	}
+	if req.FileName == "" {
+		req.FileName = header.Filename
+	}
+	if req.FileFormat == "" {
+		req.FileFormat = strings.TrimPrefix(filepath.Ext(req.FileName), ".")
+	}
+
+	if value := r.FormValue("temperature"); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid temperature: %w", err)
+		}
+		req.Generation = &models.GenerationParams{Temperature: &temperature}
+	}
+	if value := r.FormValue("max_tokens"); value != "" {
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid max_tokens: %w", err)
+		}
+		if req.Generation == nil {
+			req.Generation = &models.GenerationParams{}
+		}
+		req.Generation.MaxTokens = &maxTokens
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+func jsonUpload(t *testing.T, size int) (string, []byte) {
+	file := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, size))
+	return "application/json", []byte(fmt.Sprintf(`{"file_name":"a.png","file_format":"png","prompt":"explain","file_base64":%q}`, file))
+}
+
+func multipartUpload(t *testing.T, size int) (string, []byte) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte{0xff}, size))
+	form.WriteField("prompt", "explain")
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return form.FormDataContentType(), body.Bytes()
+}
+
+// files of up to UPLOAD_MAX_SIZE pass in both encodings
+func TestDecodeExplainRequestLimit(t *testing.T) {
+	upload := config.UploadConfig{MaxSize: 1000, MaxMemory: 1 << 20}
+
+	tests := map[string]struct {
+		encode func(t *testing.T, size int) (string, []byte)
+		size   int
+		want   int
+	}{
+		"json at the limit":         {jsonUpload, 1000, http.StatusOK},
+		"json above the limit":      {jsonUpload, 1001, http.StatusRequestEntityTooLarge},
+		"multipart at the limit":    {multipartUpload, 1000, http.StatusOK},
+		"multipart above the limit": {multipartUpload, 1001, http.StatusRequestEntityTooLarge},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			contentType, body := tc.encode(t, tc.size)
+			r := httptest.NewRequest(http.MethodPost, "/explain", bytes.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+
+			req, ok := decodeExplainRequest(w, r, upload)
+			if !ok {
+				if w.Code != tc.want {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+				}
+				return
+			}
+			if tc.want != http.StatusOK {
+				t.Fatalf("request accepted, want status %d", tc.want)
+			}
+			if req.FileData != nil {
+				t.Fatal("raw file buffered by the decoder")
+			}
+			if data, err := req.File(); err != nil || len(data) != tc.size {
+				t.Fatalf("file = %d bytes, err %v, want %d bytes", len(data), err, tc.size)
+			}
+		})
+	}
+}
+
+// both encodings give the same file, so cache keys don't depend on the upload kind
+func TestDecodeExplainRequestEncodings(t *testing.T) {
+	upload := config.UploadConfig{MaxSize: 1 << 20, MaxMemory: 100}
+	decode := func(encode func(t *testing.T, size int) (string, []byte)) *models.ExplainRequest {
+		contentType, body := encode(t, 5000)
+		r := httptest.NewRequest(http.MethodPost, "/explain", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		req, ok := decodeExplainRequest(httptest.NewRecorder(), r, upload)
+		if !ok {
+			t.Fatal("request rejected")
+		}
+		return req
+	}
+
+	fromJSON, fromMultipart := decode(jsonUpload), decode(multipartUpload)
+	if fromJSON.FileBase64 != fromMultipart.FileBase64 {
+		t.Error("multipart file encodes differently from the JSON one")
+	}
+	if fromJSON.FileHash() != fromMultipart.FileHash() {
+		t.Errorf("file hashes differ: %s and %s", fromJSON.FileHash(), fromMultipart.FileHash())
+	}
+}
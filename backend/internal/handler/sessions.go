@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-chi/chi/v5"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
+)
+
+type sessionService interface {
+	Create(ctx context.Context, req *models.ExplainRequest) (*models.SessionCreateResponse, error)
+	Send(ctx context.Context, id string, req *models.SessionMessageRequest) (*models.ExplainResponse, error)
+	SendStream(ctx, waitCtx context.Context, id string, req *models.SessionMessageRequest) (<-chan models.StreamChunk, error)
+}
+
+type SessionHandler struct {
+	service sessionService
+	streams streamHub
+	upload  config.UploadConfig
+}
+
+func NewSessionHandler(service sessionService, streams streamHub, upload config.UploadConfig) *SessionHandler {
+	return &SessionHandler{
+		service: service,
+		streams: streams,
+		upload:  upload,
+	}
+}
+
+// Create godoc
+// @Summary Create conversation session
+// @Description Upload and preprocess a diagram once to ask follow-up questions about it. File is sent as base64 string in JSON or as a raw "file" part of multipart/form-data. Optional prompt is kept as diagram context.
+// @Tags sessions
+// @Accept json,mpfd
+// @Produce json
+// @Param request body models.ExplainRequest true "Diagram"
+// @Success 201 {object} models.SessionCreateResponse
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /sessions [post]
+func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeExplainRequest(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// Message godoc
+// @Summary Ask about session diagram
+// @Description Answer a question about the session diagram taking previous messages into account
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param request body models.SessionMessageRequest true "Message"
+// @Success 200 {object} models.ExplainResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Failure 504 {object} map[string]string
+// @Router /sessions/{id}/messages [post]
+func (h *SessionHandler) Message(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeSessionMessage(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.Send(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// MessageStream godoc
+// @Summary Stream answer about session diagram
+// @Description Stream answer tokens for a question about the session diagram, events and resumption with Last-Event-ID are the same as for /explain/stream
+// @Tags sessions
+// @Accept json
+// @Produce text/event-stream
+// @Param id path string true "Session ID"
+// @Param request body models.SessionMessageRequest true "Message"
+// @Param Last-Event-ID header string false "Last received event id to resume the stream"
+// @Success 200 {object} models.StreamChunk "Stream of tokens (SSE)"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /sessions/{id}/messages/stream [post]
+func (h *SessionHandler) MessageStream(w http.ResponseWriter, r *http.Request) {
+	if resumeStream(w, r, h.streams) {
+		return
+	}
+
+	req, ok := decodeSessionMessage(w, r, h.upload)
+	if !ok {
+		return
+	}
+
+	// the stream context is detached from the request, a client gone while
+	// the previous message is answered must not keep waiting for the session
+	id := chi.URLParam(r, "id")
+	events, err := h.streams.Start(r.Context(), func(ctx context.Context) (<-chan models.StreamChunk, error) {
+		return h.service.SendStream(ctx, r.Context(), id, req)
+	})
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	writeStream(w, events, h.streams.Heartbeat())
+}
+
+func decodeSessionMessage(w http.ResponseWriter, r *http.Request, upload config.UploadConfig) (*models.SessionMessageRequest, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, upload.MaxSize)
+
+	var req models.SessionMessageRequest
+	if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, fmt.Sprintf("invalid JSON: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("request validation failed: %s", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrSessionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeServiceError(w, err)
+}
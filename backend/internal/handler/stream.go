@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/service"
+)
+
+type streamHub interface {
+	Heartbeat() time.Duration
+	Start(ctx context.Context, start func(ctx context.Context) (<-chan models.StreamChunk, error)) (<-chan models.StreamEvent, error)
+	Resume(ctx context.Context, lastEventID string) (<-chan models.StreamEvent, error)
+}
+
+// resumeStream continues the stream for a client reconnecting with Last-Event-ID,
+// returns false for new streams
+func resumeStream(w http.ResponseWriter, r *http.Request, streams streamHub) bool {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		return false
+	}
+
+	events, err := streams.Resume(r.Context(), lastEventID)
+	if err != nil {
+		if errors.Is(err, service.ErrStreamNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return true
+		}
+		http.Error(w, fmt.Sprintf("service error: %s", err), http.StatusInternalServerError)
+		return true
+	}
+
+	writeStream(w, events, streams.Heartbeat())
+	return true
+}
+
+// writeStream sends events as SSE with ids: "queued" and "meta" events, preprocessing stages,
+// "message" events with deltas, "usage", then "done" or "error". Comment heartbeats keep
+// the connection alive while nothing happens, e.g. during file conversion
+func writeStream(w http.ResponseWriter, events <-chan models.StreamEvent, heartbeat time.Duration) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher := http.NewResponseController(w)
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			more := writeEvent(w, event)
+			flusher.Flush()
+			if !more {
+				return
+			}
+			ticker.Reset(heartbeat)
+		}
+	}
+}
+
+// writeEvent returns false after the last event of the stream
+func writeEvent(w http.ResponseWriter, event models.StreamEvent) bool {
+	chunk := event.Chunk
+
+	switch {
+	case chunk.Meta != nil:
+		data, err := sonic.Marshal(chunk.Meta)
+		if err == nil {
+			fmt.Fprintf(w, "id: %s\nevent: meta\ndata: %s\n\n", event.ID, data)
+		}
+		return true
+	case chunk.Stage != nil:
+		data, err := sonic.Marshal(chunk.Stage)
+		if err == nil {
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, chunk.Stage.Name, data)
+		}
+		return true
+	case chunk.Err != nil:
+		writeErrorEvent(w, event.ID, chunk.Err.Error())
+		return false
+	}
+
+	data, err := sonic.Marshal(chunk)
+	if err != nil {
+		writeErrorEvent(w, event.ID, fmt.Sprintf("marshal error %v", err))
+		return false
+	}
+
+	fmt.Fprintf(w, "id: %s\nevent: message\ndata: %s\n\n", event.ID, data)
+	if chunk.Done {
+		fmt.Fprintf(w, "event: done\ndata: {}\n\n")
+		return false
+	}
+	return true
+}
+
+// writeErrorEvent sends every line of msg as its own data line, a line break
+// inside a single data line would end the event early
+func writeErrorEvent(w http.ResponseWriter, id, msg string) {
+	fmt.Fprintf(w, "id: %s\nevent: error\n", id)
+	msg = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(msg)
+	for _, line := range strings.Split(msg, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+func TestWriteEventError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"single line": {
+			err:  errors.New("backend unavailable"),
+			want: "id: 7\nevent: error\ndata: backend unavailable\n\n",
+		},
+		"multi line": {
+			err:  errors.New("upstream error:\nline one\r\nline two\rline three"),
+			want: "id: 7\nevent: error\ndata: upstream error:\ndata: line one\ndata: line two\ndata: line three\n\n",
+		},
+		"forged event": {
+			err:  errors.New("bad\n\nevent: done\ndata: {}"),
+			want: "id: 7\nevent: error\ndata: bad\ndata: \ndata: event: done\ndata: data: {}\n\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if writeEvent(w, models.StreamEvent{ID: "7", Chunk: models.StreamChunk{Err: tc.err}}) {
+				t.Error("writeEvent = true after an error")
+			}
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("event =\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
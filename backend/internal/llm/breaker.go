@@ -0,0 +1,69 @@
+package llm
+
+import "time"
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuit is a backend circuit breaker. An open circuit takes no calls until the cooldown
+// passes, then a single probe call decides whether it's closed or opened again
+type circuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (c *circuit) available(now time.Time, cooldown time.Duration) bool {
+	switch c.state {
+	case circuitOpen:
+		return now.Sub(c.openedAt) >= cooldown
+	case circuitHalfOpen:
+		return !c.probing
+	}
+	return true
+}
+
+// enter reserves the probe call of a circuit after cooldown, reports whether it became half-open
+func (c *circuit) enter() bool {
+	switch c.state {
+	case circuitOpen:
+		c.state, c.probing = circuitHalfOpen, true
+		return true
+	case circuitHalfOpen:
+		c.probing = true
+	}
+	return false
+}
+
+// fail counts a failed call, reports whether the circuit has been opened
+func (c *circuit) fail(now time.Time, maxFailures int) bool {
+	c.failures++
+	c.probing = false
+
+	if c.state == circuitHalfOpen || (c.state == circuitClosed && c.failures >= maxFailures) {
+		c.state, c.openedAt = circuitOpen, now
+		return true
+	}
+	return false
+}
+
+// succeed resets failures, reports whether the circuit has been closed
+func (c *circuit) succeed() bool {
+	c.failures, c.probing = 0, false
+	if c.state == circuitClosed {
+		return false
+	}
+	c.state = circuitClosed
+	return true
+}
+
+// abort frees the probe of a call that ended without a result
+func (c *circuit) abort() {
+	c.probing = false
+}
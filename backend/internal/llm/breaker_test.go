@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuit(t *testing.T) {
+	const cooldown = time.Minute
+	var c circuit
+	now := time.Now()
+
+	if c.fail(now, 2) {
+		t.Fatal("circuit opened before MaxFailures")
+	}
+	if !c.fail(now, 2) || c.state != circuitOpen {
+		t.Fatal("circuit not opened after MaxFailures")
+	}
+	if c.available(now.Add(cooldown/2), cooldown) {
+		t.Fatal("open circuit available before the cooldown")
+	}
+
+	// a single probe after the cooldown
+	later := now.Add(cooldown)
+	if !c.available(later, cooldown) || !c.enter() || c.state != circuitHalfOpen {
+		t.Fatal("circuit not half-open after the cooldown")
+	}
+	if c.available(later, cooldown) {
+		t.Fatal("second call admitted while probing")
+	}
+	c.abort()
+	if !c.available(later, cooldown) {
+		t.Fatal("aborted probe kept the circuit busy")
+	}
+
+	// a failed probe opens the circuit at once
+	c.enter()
+	if !c.fail(later, 2) || c.available(later, cooldown) {
+		t.Fatal("failed probe didn't open the circuit again")
+	}
+
+	c.enter()
+	if !c.succeed() || c.state != circuitClosed || c.failures != 0 {
+		t.Fatalf("successful probe didn't close the circuit: %+v", c)
+	}
+	if c.succeed() {
+		t.Fatal("closed circuit reported closing again")
+	}
+}
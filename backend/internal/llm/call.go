@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/ssestream"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// Errors of model calls, the original error is wrapped as well
+var (
+	ErrUnavailable = errors.New("model backend unavailable")
+	ErrTimeout     = errors.New("model backend timeout")
+	ErrBadGateway  = errors.New("model backend error")
+
+	errCallTimeout       = errors.New("call timeout")
+	errFirstTokenTimeout = errors.New("first token timeout")
+	errIdleTimeout       = errors.New("stream idle timeout")
+)
+
+// Complete runs a chat completion on the least loaded backend of the first available model.
+// The params model is replaced with the backend one
+func (p *Pool) Complete(ctx context.Context, params *openai.ChatCompletionNewParams, models ...string) (*openai.ChatCompletion, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		b, err := p.acquire(models...)
+		if err != nil {
+			// all backends failed on previous attempts
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+		params.Model = shared.ChatModel(b.Model)
+
+		callCtx, cancel := context.WithTimeoutCause(ctx, p.callTimeout, errCallTimeout)
+		resp, err := b.Client.Chat.Completions.New(callCtx, *params)
+		err, failure := classify(ctx, callCtx, err)
+		cancel()
+		p.release(b, err, failure)
+
+		if err == nil {
+			return resp, nil
+		}
+		if !p.retry(ctx, b, err, attempt) {
+			return nil, err
+		}
+		lastErr = err
+	}
+}
+
+// Stream is a chat completion stream. Calls failing before the first chunk are retried,
+// possibly on another backend
+type Stream struct {
+	pool   *Pool
+	ctx    context.Context
+	params *openai.ChatCompletionNewParams
+	models []string
+
+	backend *Backend
+	stream  *ssestream.Stream[openai.ChatCompletionChunk]
+	callCtx context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	started bool
+	attempt int
+	err     error
+}
+
+// Stream opens a chat completion stream like Complete, Close must be called when the stream is done
+func (p *Pool) Stream(ctx context.Context, params *openai.ChatCompletionNewParams, models ...string) (*Stream, error) {
+	s := &Stream{
+		pool:   p,
+		ctx:    ctx,
+		params: params,
+		models: models,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Stream) open() error {
+	b, err := s.pool.acquire(s.models...)
+	if err != nil {
+		return err
+	}
+	s.backend = b
+	s.params.Model = shared.ChatModel(b.Model)
+
+	callCtx, cancel := context.WithCancelCause(s.ctx)
+	s.callCtx, s.cancel = callCtx, cancel
+	s.timer = time.AfterFunc(s.pool.firstTokenTimeout, func() { cancel(errFirstTokenTimeout) })
+	s.stream = b.Client.Chat.Completions.NewStreaming(s.callCtx, *s.params)
+	return nil
+}
+
+// Next waits for the next chunk at most the first token or the idle timeout
+func (s *Stream) Next() bool {
+	for s.stream != nil {
+		if s.started {
+			s.timer.Reset(s.pool.idleTimeout)
+		}
+		if s.stream.Next() {
+			// the consumer time isn't counted as idle
+			s.timer.Stop()
+			if !s.started {
+				s.started = true
+				cancel := s.cancel
+				s.timer = time.AfterFunc(s.pool.idleTimeout, func() { cancel(errIdleTimeout) })
+				s.timer.Stop()
+			}
+			return true
+		}
+
+		err, failure := classify(s.ctx, s.callCtx, s.stream.Err())
+		b := s.backend
+		s.finish(err, failure)
+		if err == nil {
+			return false
+		}
+		if s.started || !s.pool.retry(s.ctx, b, err, s.attempt) {
+			s.err = err
+			return false
+		}
+
+		s.attempt++
+		if s.open() != nil {
+			s.err = err
+			return false
+		}
+	}
+	return false
+}
+
+func (s *Stream) Current() openai.ChatCompletionChunk {
+	return s.stream.Current()
+}
+
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Model returns the model of the current backend
+func (s *Stream) Model() string {
+	return s.backend.Model
+}
+
+// Close releases the backend of an unfinished stream
+func (s *Stream) Close() {
+	if s.stream != nil {
+		s.finish(context.Canceled, false)
+	}
+}
+
+func (s *Stream) finish(err error, failure bool) {
+	s.timer.Stop()
+	s.stream.Close()
+	s.cancel(nil)
+	s.pool.release(s.backend, err, failure)
+	s.stream = nil
+}
+
+// retry reports whether the failed attempt is retried and waits a jittered backoff
+func (p *Pool) retry(ctx context.Context, b *Backend, err error, attempt int) bool {
+	if !errors.Is(err, ErrUnavailable) || attempt >= p.retries {
+		return false
+	}
+
+	reason := "unavailable"
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		reason = http.StatusText(apiErr.StatusCode)
+	}
+	metrics.LLMRetry(b.Name, reason)
+	p.logger.Printf("backend %s call failed, retry %d/%d: %v\n", b.Name, attempt+1, p.retries, err)
+
+	if p.backoff <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(rand.N(p.backoff << attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// classify wraps err into ErrUnavailable, ErrTimeout or ErrBadGateway and reports whether
+// it is the backend failure. Errors of the caller context are returned as is
+func classify(ctx, callCtx context.Context, err error) (error, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrTimeout, err), false
+	case ctx.Err() != nil:
+		return err, false
+	case errors.Is(context.Cause(callCtx), errFirstTokenTimeout):
+		// a backend which doesn't start answering is stuck
+		return fmt.Errorf("%w: %w", ErrTimeout, err), true
+	case errors.Is(context.Cause(callCtx), errCallTimeout), errors.Is(context.Cause(callCtx), errIdleTimeout):
+		// slow generations, the backend is alive
+		return fmt.Errorf("%w: %w", ErrTimeout, err), false
+	}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable:
+			return fmt.Errorf("%w: %w", ErrUnavailable, err), true
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return fmt.Errorf("%w: %w", ErrBadGateway, err), true
+		default:
+			// the request is rejected, the backend itself is fine
+			return fmt.Errorf("%w: %w", ErrBadGateway, err), false
+		}
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err), true
+	}
+	return fmt.Errorf("%w: %w", ErrBadGateway, err), true
+}
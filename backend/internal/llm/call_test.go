@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/openai/openai-go/v3"
+)
+
+const (
+	testCompletion = `{"id":"c","object":"chat.completion","created":0,"model":"test",` +
+		`"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"answer"}}]}`
+	testChunk = `{"id":"c","object":"chat.completion.chunk","created":0,"model":"test",` +
+		`"choices":[{"index":0,"delta":{"content":"answer"}}]}`
+)
+
+// newTestPool serves a single backend answering with the statuses one per call, the last one
+// is repeated. 200 answers are completions or streams, the number of calls is counted
+func newTestPool(t *testing.T, cfg config.OpenAIConfig, delay time.Duration, statuses ...int) (*Pool, *atomic.Int64) {
+	t.Helper()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(int(calls.Add(1))-1, len(statuses)-1)]
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if status != http.StatusOK {
+			http.Error(w, `{"error":{"message":"failed"}}`, status)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if !streamRequest(body) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, testCompletion)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", testChunk)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg.BaseURL, cfg.Model = srv.URL, "test"
+	if cfg.CallTimeout == 0 {
+		cfg.CallTimeout = time.Minute
+	}
+	if cfg.FirstTokenTimeout == 0 {
+		cfg.FirstTokenTimeout, cfg.StreamIdleTimeout = time.Minute, time.Minute
+	}
+	if cfg.BreakerCooldown == 0 {
+		cfg.BreakerCooldown = time.Minute
+	}
+	pool, err := NewPool(log.New(io.Discard, "", 0), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool, &calls
+}
+
+func streamRequest(body []byte) bool {
+	var req struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal(body, &req) == nil && req.Stream
+}
+
+func testParams() *openai.ChatCompletionNewParams {
+	return &openai.ChatCompletionNewParams{Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("explain")}}
+}
+
+func TestCompleteRetries(t *testing.T) {
+	tests := map[string]struct {
+		statuses []int
+		wantErr  error
+		calls    int64
+		failures int
+	}{
+		"unavailable then answered": {statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, calls: 2},
+		"rate limited":              {statuses: []int{http.StatusTooManyRequests}, wantErr: ErrUnavailable, calls: 3, failures: 3},
+		// 5xx other than 503 are not retried, the answer would be the same
+		"server error": {statuses: []int{http.StatusInternalServerError}, wantErr: ErrBadGateway, calls: 1, failures: 1},
+		// rejected requests are not the backend fault
+		"bad request": {statuses: []int{http.StatusBadRequest}, wantErr: ErrBadGateway, calls: 1},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			pool, calls := newTestPool(t, config.OpenAIConfig{Retries: 2, MaxFailures: 10}, 0, tc.statuses...)
+
+			_, err := pool.Complete(context.Background(), testParams())
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil) != (err == nil) {
+				t.Fatalf("Complete error = %v, want %v", err, tc.wantErr)
+			}
+			if calls.Load() != tc.calls {
+				t.Errorf("calls = %d, want %d", calls.Load(), tc.calls)
+			}
+			if got := pool.backends[0].circuit.failures; got != tc.failures {
+				t.Errorf("backend failures = %d, want %d", got, tc.failures)
+			}
+		})
+	}
+}
+
+func TestCompleteOpensCircuit(t *testing.T) {
+	pool, calls := newTestPool(t, config.OpenAIConfig{MaxFailures: 2}, 0, http.StatusInternalServerError)
+
+	for range 2 {
+		if _, err := pool.Complete(context.Background(), testParams()); !errors.Is(err, ErrBadGateway) {
+			t.Fatalf("Complete error = %v, want ErrBadGateway", err)
+		}
+	}
+	if _, err := pool.Complete(context.Background(), testParams()); !errors.Is(err, ErrNoHealthyBackend) {
+		t.Fatalf("Complete error = %v, want ErrNoHealthyBackend with an open circuit", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestCompleteCallTimeout(t *testing.T) {
+	pool, _ := newTestPool(t, config.OpenAIConfig{CallTimeout: 50 * time.Millisecond, MaxFailures: 1}, time.Second, http.StatusOK)
+
+	if _, err := pool.Complete(context.Background(), testParams()); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Complete error = %v, want ErrTimeout", err)
+	}
+	// a slow generation doesn't open the circuit
+	if state := pool.backends[0].circuit.state; state != circuitClosed {
+		t.Errorf("circuit state = %d, want closed", state)
+	}
+}
+
+func TestStreamRetriesBeforeFirstToken(t *testing.T) {
+	pool, calls := newTestPool(t, config.OpenAIConfig{Retries: 1, MaxFailures: 10}, 0, http.StatusServiceUnavailable, http.StatusOK)
+
+	stream, err := pool.Stream(context.Background(), testParams())
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer stream.Close()
+
+	var content string
+	for stream.Next() {
+		for _, choice := range stream.Current().Choices {
+			content += choice.Delta.Content
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	if content != "answer" || calls.Load() != 2 {
+		t.Errorf("content = %q after %d calls, want %q after 2", content, calls.Load(), "answer")
+	}
+}
+
+func TestStreamFirstTokenTimeout(t *testing.T) {
+	cfg := config.OpenAIConfig{FirstTokenTimeout: 50 * time.Millisecond, StreamIdleTimeout: time.Minute, MaxFailures: 1}
+	pool, _ := newTestPool(t, cfg, time.Second, http.StatusOK)
+
+	stream, err := pool.Stream(context.Background(), testParams())
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer stream.Close()
+
+	if stream.Next() {
+		t.Fatal("chunk received from a stuck backend")
+	}
+	if !errors.Is(stream.Err(), ErrTimeout) {
+		t.Fatalf("stream error = %v, want ErrTimeout", stream.Err())
+	}
+	// a backend which doesn't start answering is stuck
+	if state := pool.backends[0].circuit.state; state != circuitOpen {
+		t.Errorf("circuit state = %d, want open", state)
+	}
+}
+
+func TestClassifyContext(t *testing.T) {
+	callErr := errors.New("call failed")
+	deadline, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	withCause := func(cause error) context.Context {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(cause)
+		return ctx
+	}
+
+	tests := map[string]struct {
+		ctx, callCtx context.Context
+		wantErr      error
+		failure      bool
+	}{
+		"caller deadline":  {ctx: deadline, callCtx: deadline, wantErr: ErrTimeout},
+		"caller cancelled": {ctx: cancelled, callCtx: cancelled, wantErr: callErr},
+		"first token":      {ctx: context.Background(), callCtx: withCause(errFirstTokenTimeout), wantErr: ErrTimeout, failure: true},
+		"call timeout":     {ctx: context.Background(), callCtx: withCause(errCallTimeout), wantErr: ErrTimeout},
+		"stream idle":      {ctx: context.Background(), callCtx: withCause(errIdleTimeout), wantErr: ErrTimeout},
+		"other error":      {ctx: context.Background(), callCtx: context.Background(), wantErr: ErrBadGateway, failure: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err, failure := classify(tc.ctx, tc.callCtx, callErr)
+			if !errors.Is(err, tc.wantErr) || !errors.Is(err, callErr) {
+				t.Errorf("error = %v, want %v wrapping the call error", err, tc.wantErr)
+			}
+			if failure != tc.failure {
+				t.Errorf("failure = %v, want %v", failure, tc.failure)
+			}
+		})
+	}
+
+	if err, failure := classify(context.Background(), context.Background(), nil); err != nil || failure {
+		t.Errorf("classify(nil) = %v, %v", err, failure)
+	}
+}
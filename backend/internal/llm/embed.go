@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// Embedder turns texts into unit vectors with an OpenAI-compatible /embeddings endpoint,
+// llama.cpp serves it when started with --embeddings
+type Embedder struct {
+	client  openai.Client
+	model   string
+	timeout time.Duration
+}
+
+func NewEmbedder(baseURL, apiKey, model string, timeout time.Duration) *Embedder {
+	return &Embedder{
+		client: openai.NewClient(
+			option.WithAPIKey(apiKey),
+			option.WithBaseURL(strings.TrimRight(baseURL, "/")),
+			option.WithMaxRetries(0),
+		),
+		model:   model,
+		timeout: timeout,
+	}
+}
+
+// Embed returns the normalized embedding of text, so the dot product of two embeddings is their cosine similarity
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	resp, err := e.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModel(e.model),
+		Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String(text)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding error: %w", err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embedding error: empty response")
+	}
+
+	values := resp.Data[0].Embedding
+	var norm float64
+	for _, v := range values {
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil, fmt.Errorf("embedding error: zero vector")
+	}
+	norm = math.Sqrt(norm)
+
+	embedding := make([]float32, len(values))
+	for i, v := range values {
+		embedding[i] = float32(v / norm)
+	}
+	return embedding, nil
+}
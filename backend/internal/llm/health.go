@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+)
+
+// slot is a llama.cpp server slot, older servers report state instead of is_processing
+type slot struct {
+	IsProcessing *bool `json:"is_processing"`
+	State        *int  `json:"state"`
+}
+
+// Run checks backends every health interval until ctx is done. Unhealthy backends
+// get calls again once their check passes
+func (p *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.checkAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			p.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) checkAll(ctx context.Context) {
+	for _, b := range p.backends {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.check(ctx, b)
+		}()
+	}
+	p.wg.Wait()
+}
+
+func (p *Pool) check(ctx context.Context, b *Backend) {
+	err := p.get(ctx, b.baseURL+"/models", nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if b.healthy.CompareAndSwap(true, false) {
+			p.logger.Printf("backend %s is unhealthy: %v\n", b.Name, err)
+			metrics.LLMBackendHealthy(b.Name, false)
+		}
+		return
+	}
+
+	if p.readSlots {
+		b.freeSlots.Store(p.freeSlots(ctx, b))
+		metrics.LLMBackendFreeSlots(b.Name, b.freeSlots.Load())
+	}
+
+	if b.healthy.CompareAndSwap(false, true) {
+		p.logger.Printf("backend %s is healthy again\n", b.Name)
+		metrics.LLMBackendHealthy(b.Name, true)
+	}
+}
+
+// freeSlots reads llama.cpp /slots, which is served next to /v1. Returns -1 when
+// the endpoint is missing or disabled
+func (p *Pool) freeSlots(ctx context.Context, b *Backend) int64 {
+	var slots []slot
+	if err := p.get(ctx, strings.TrimSuffix(b.baseURL, "/v1")+"/slots", &slots); err != nil {
+		return -1
+	}
+
+	var free int64
+	for _, s := range slots {
+		busy := (s.IsProcessing != nil && *s.IsProcessing) || (s.State != nil && *s.State != 0)
+		if !busy {
+			free++
+		}
+	}
+	return free
+}
+
+func (p *Pool) get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+var ErrNoHealthyBackend = fmt.Errorf("%w: no healthy backend", ErrUnavailable)
+
+// Backend is a single OpenAI-compatible server
+type Backend struct {
+	Name   string
+	Model  string
+	Client openai.Client
+
+	baseURL     string
+	outstanding atomic.Int64
+	healthy     atomic.Bool
+
+	// free llama.cpp slots at the last health check, -1 when unknown
+	freeSlots atomic.Int64
+
+	// guarded by the pool mutex
+	circuit circuit
+}
+
+// Pool routes calls to the available backend with the least outstanding requests
+type Pool struct {
+	logger     *log.Logger
+	backends   []*Backend
+	httpClient *http.Client
+	apiKey     string
+	interval   time.Duration
+	readSlots  bool
+
+	maxFailures int
+	cooldown    time.Duration
+
+	callTimeout       time.Duration
+	firstTokenTimeout time.Duration
+	idleTimeout       time.Duration
+	retries           int
+	backoff           time.Duration
+
+	// rotates between equally loaded backends
+	next atomic.Uint64
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+}
+
+func NewPool(logger *log.Logger, cfg config.OpenAIConfig) (*Pool, error) {
+	entries := cfg.Backends
+	if len(entries) == 0 {
+		entries = []string{cfg.BaseURL}
+	}
+
+	p := &Pool{
+		logger:            logger,
+		httpClient:        &http.Client{Timeout: cfg.HealthTimeout},
+		apiKey:            cfg.APIKey,
+		interval:          cfg.HealthInterval,
+		readSlots:         cfg.ReadSlots,
+		maxFailures:       max(cfg.MaxFailures, 1),
+		cooldown:          cfg.BreakerCooldown,
+		callTimeout:       cfg.CallTimeout,
+		firstTokenTimeout: cfg.FirstTokenTimeout,
+		idleTimeout:       cfg.StreamIdleTimeout,
+		retries:           max(cfg.Retries, 0),
+		backoff:           cfg.RetryBackoff,
+	}
+
+	for _, entry := range entries {
+		baseURL, model, _ := strings.Cut(strings.TrimSpace(entry), "|")
+		baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+		if baseURL == "" {
+			return nil, fmt.Errorf("invalid backend %q: empty url", entry)
+		}
+		if model = strings.TrimSpace(model); model == "" {
+			model = cfg.Model
+		}
+
+		b := &Backend{
+			Name:    baseURL,
+			Model:   model,
+			baseURL: baseURL,
+			Client: openai.NewClient(
+				option.WithAPIKey(cfg.APIKey),
+				option.WithBaseURL(baseURL),
+				// retries are made by the pool, possibly on another backend
+				option.WithMaxRetries(0),
+			),
+		}
+		b.healthy.Store(true)
+		b.freeSlots.Store(-1)
+		metrics.LLMBackendHealthy(b.Name, true)
+		metrics.LLMBackendCircuit(b.Name, int(circuitClosed))
+		p.backends = append(p.backends, b)
+	}
+
+	if err := ParseRoutes(cfg.Routes).validate(p.backends); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Backends returns all backends of the pool including unavailable ones
+func (p *Pool) Backends() []*Backend {
+	return p.backends
+}
+
+func (b *Backend) Healthy() bool {
+	return b.healthy.Load()
+}
+
+// acquire picks a backend serving the first available of models, any model if none given.
+// release must be called when the call is finished
+func (p *Pool) acquire(models ...string) (*Backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(models) == 0 {
+		if b := p.leastLoaded(""); b != nil {
+			return b, nil
+		}
+		return nil, ErrNoHealthyBackend
+	}
+
+	for i, model := range models {
+		if b := p.leastLoaded(model); b != nil {
+			if i > 0 {
+				p.logger.Printf("model %s is unavailable, falling back to %s\n", models[0], model)
+			}
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for models %s", ErrNoHealthyBackend, strings.Join(models, ", "))
+}
+
+// leastLoaded reserves the available backend with the least outstanding requests.
+// Free llama.cpp slots reduce the load when slots are read
+func (p *Pool) leastLoaded(model string) *Backend {
+	var (
+		best     *Backend
+		bestLoad int64
+		now      = time.Now()
+	)
+
+	offset := p.next.Add(1)
+	for i := range p.backends {
+		b := p.backends[(int(offset)+i)%len(p.backends)]
+		if !b.healthy.Load() || !b.circuit.available(now, p.cooldown) || (model != "" && b.Model != model) {
+			continue
+		}
+
+		load := b.outstanding.Load()
+		if free := b.freeSlots.Load(); free >= 0 {
+			load -= free
+		}
+		if best == nil || load < bestLoad {
+			best, bestLoad = b, load
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	if best.circuit.enter() {
+		p.logger.Printf("backend %s circuit is half-open, probing\n", best.Name)
+		metrics.LLMBackendCircuit(best.Name, int(circuitHalfOpen))
+	}
+	metrics.LLMBackendOutstanding(best.Name, best.outstanding.Add(1))
+	return best
+}
+
+// release reports the call result to the circuit breaker. Calls which are not
+// the backend fault, like cancellation or 4xx, don't change the circuit
+func (p *Pool) release(b *Backend, err error, failure bool) {
+	metrics.LLMBackendOutstanding(b.Name, b.outstanding.Add(-1))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case failure:
+		metrics.LLMBackendRequest(b.Name, "failed")
+		if b.circuit.fail(time.Now(), p.maxFailures) {
+			p.logger.Printf("backend %s circuit is open after %d failures: %v\n", b.Name, b.circuit.failures, err)
+			metrics.LLMBackendCircuit(b.Name, int(circuitOpen))
+		}
+	case err != nil:
+		metrics.LLMBackendRequest(b.Name, "cancelled")
+		b.circuit.abort()
+	default:
+		metrics.LLMBackendRequest(b.Name, "success")
+		if b.circuit.succeed() {
+			p.logger.Printf("backend %s circuit is closed\n", b.Name)
+			metrics.LLMBackendCircuit(b.Name, int(circuitClosed))
+		}
+	}
+}
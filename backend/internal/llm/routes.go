@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// route keys for requests without a format rule
+const (
+	RouteText   = "text"
+	RouteVision = "vision"
+)
+
+// Routes maps a file format or a capability to a chain of models, the first model
+// with a healthy backend serves the request
+type Routes map[string][]string
+
+// ParseRoutes reads "key: model|fallback" rules, keys are file formats, "text" or "vision"
+func ParseRoutes(rules map[string]string) Routes {
+	routes := make(Routes, len(rules))
+	for key, chain := range rules {
+		var models []string
+		for _, model := range strings.Split(chain, "|") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		routes[strings.ToLower(strings.TrimSpace(key))] = models
+	}
+	return routes
+}
+
+// Models returns the chain for the request. A format rule wins over the capability one,
+// but requests with images keep only its models of the "vision" rule and use the "vision"
+// chain when none is left, e.g. drawio files rendered to an image. nil means any backend
+func (r Routes) Models(format string, vision bool) []string {
+	models, ok := r[format]
+	switch {
+	case ok && vision:
+		visionModels, known := r[RouteVision]
+		if !known {
+			return models
+		}
+		capable := slices.DeleteFunc(slices.Clone(models), func(model string) bool {
+			return !slices.Contains(visionModels, model)
+		})
+		if len(capable) > 0 {
+			return capable
+		}
+		return visionModels
+	case ok:
+		return models
+	case vision:
+		return r[RouteVision]
+	default:
+		return r[RouteText]
+	}
+}
+
+// validate checks that every routed model is served by some backend
+func (r Routes) validate(backends []*Backend) error {
+	for key, models := range r {
+		if len(models) == 0 {
+			return fmt.Errorf("route %q has no models", key)
+		}
+		for _, model := range models {
+			served := slices.ContainsFunc(backends, func(b *Backend) bool { return b.Model == model })
+			if !served {
+				return fmt.Errorf("route %q: no backend serves model %q", key, model)
+			}
+		}
+	}
+	return nil
+}
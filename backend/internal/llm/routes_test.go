@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRoutesModels(t *testing.T) {
+	routes := ParseRoutes(map[string]string{
+		"text":   "qwen|minicpm",
+		"vision": "minicpm|llava",
+		"drawio": "qwen",
+		"bpmn":   "qwen | llava",
+		"svg":    "minicpm",
+	})
+	noVision := ParseRoutes(map[string]string{"drawio": "qwen"})
+
+	tests := map[string]struct {
+		routes Routes
+		format string
+		vision bool
+		want   []string
+	}{
+		"format rule":                {routes, "drawio", false, []string{"qwen"}},
+		"format rule without vision": {routes, "drawio", true, []string{"minicpm", "llava"}},
+		"format rule keeps vision":   {routes, "bpmn", true, []string{"llava"}},
+		"vision format rule":         {routes, "svg", true, []string{"minicpm"}},
+		"text rule":                  {routes, "txt", false, []string{"qwen", "minicpm"}},
+		"vision rule":                {routes, "png", true, []string{"minicpm", "llava"}},
+		"unknown vision capability":  {noVision, "drawio", true, []string{"qwen"}},
+		"no rule":                    {noVision, "png", true, nil},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.routes.Models(tc.format, tc.vision); !slices.Equal(got, tc.want) {
+				t.Errorf("Models(%q, %v) = %v, want %v", tc.format, tc.vision, got, tc.want)
+			}
+		})
+	}
+
+	// filtering must not change the configured chain
+	routes.Models("bpmn", true)
+	if got := routes["bpmn"]; !slices.Equal(got, []string{"qwen", "llava"}) {
+		t.Errorf("bpmn route changed to %v", got)
+	}
+}
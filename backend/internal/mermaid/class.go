@@ -0,0 +1,69 @@
+package mermaid
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	className   = "(?:\\w+(?:~[^~]+~)?|`[^`]+`)"
+	cardinality = `(?:"[^"]*"\s*)?`
+	relation    = `(?:<\||\*|o|<)?(?:--|\.\.)(?:\|>|\*|o|>)?`
+)
+
+var (
+	classPattern      = regexp.MustCompile(`^class\s+` + className + `(?:\["[^"]*"\])?\s*(?::::\w+)?\s*(\{)?$`)
+	relationPattern   = regexp.MustCompile(`^` + className + `\s*` + cardinality + relation + `\s*` + cardinality + className + `\s*(?::.*)?$`)
+	memberPattern     = regexp.MustCompile(`^` + className + `\s*:\s*\S`)
+	annotationPattern = regexp.MustCompile(`^<<[^<>]+>>\s*` + className + `$`)
+	classNotePattern  = regexp.MustCompile(`^note\s+(?:for\s+` + className + `\s+)?"[^"]*"$`)
+	namespacePattern  = regexp.MustCompile(`^namespace\s+\S+\s*\{$`)
+)
+
+func validateClass(lines []line) error {
+	var (
+		stack []block
+		err   error
+	)
+
+	for _, l := range lines {
+		// class bodies hold free form members until the closing brace
+		if len(stack) > 0 && stack[len(stack)-1].kind == "class" && l.text != "}" {
+			continue
+		}
+
+		switch kw := keyword(l.text); kw {
+		case "}":
+			if stack, err = closeBlock(stack, l, "class", "namespace"); err != nil {
+				return err
+			}
+		case "class":
+			m := classPattern.FindStringSubmatch(l.text)
+			if m == nil {
+				return l.errorf("invalid class declaration %q", l.text)
+			}
+			if m[1] != "" {
+				stack = append(stack, block{kind: kw, line: l})
+			}
+		case "namespace":
+			if !namespacePattern.MatchString(l.text) {
+				return l.errorf("invalid namespace %q, expected `namespace Name {`", l.text)
+			}
+			stack = append(stack, block{kind: kw, line: l})
+		case "note":
+			if !classNotePattern.MatchString(l.text) {
+				return l.errorf(`invalid note %q, expected note for Class "text"`, l.text)
+			}
+		case "direction":
+			if !directions[strings.TrimSpace(strings.TrimPrefix(l.text, kw))] {
+				return l.errorf("invalid direction %q, expected TB, TD, BT, RL or LR", l.text)
+			}
+		case "classDef", "cssClass", "style", "click", "link", "callback", "title", "accTitle", "accDescr":
+		default:
+			if !relationPattern.MatchString(l.text) && !memberPattern.MatchString(l.text) && !annotationPattern.MatchString(l.text) {
+				return l.errorf("invalid statement %q, expected relation like `A <|-- B` or member like `A : +field`", l.text)
+			}
+		}
+	}
+	return unclosed(stack)
+}
@@ -0,0 +1,85 @@
+package mermaid
+
+import "testing"
+
+func TestValidateClass(t *testing.T) {
+	tests := map[string]struct {
+		src string
+		// line of the expected syntax error, 0 for valid diagrams
+		line int
+	}{
+		"members": {
+			src: "classDiagram\nclass Order\nOrder : +String id\nOrder : +total() float",
+		},
+		"class body": {
+			src: "classDiagram\nclass Order {\n  +String id\n  -List~Item~ items\n  +total()$ float\n}",
+		},
+		"generics, labels and annotations": {
+			src: "classDiagram\nclass Box~T~\nclass Api[\"Public API\"]:::edge\n<<interface>> Api",
+		},
+		"relations": {
+			src: "classDiagram\nAnimal <|-- Dog\nCar *-- Wheel\nLibrary o-- Book\nA --> B\nC ..> D\nE ..|> F\nG -- H\nI .. J",
+		},
+		"relations with cardinality and labels": {
+			src: "classDiagram\nCustomer \"1\" --> \"*\" Order : places\nOrder \"1\" *-- \"1..*\" Item",
+		},
+		"namespace": {
+			src: "classDiagram-v2\nnamespace Shop {\nclass Order\nclass Item\n}\nOrder --> Item",
+		},
+		"notes, direction and styling": {
+			src: "classDiagram\ndirection RL\nnote \"overview\"\nnote for Order \"paid once\"\nclassDef hot fill:#f00\ncssClass \"Order\" hot",
+		},
+		"backtick names": {
+			src: "classDiagram\nclass `Order Item`\n`Order Item` --> Order",
+		},
+		"header arguments": {
+			src:  "classDiagram LR\nA --> B",
+			line: 1,
+		},
+		"unclosed class body": {
+			src:  "classDiagram\nclass Order {\n+String id",
+			line: 2,
+		},
+		"unclosed namespace": {
+			src:  "classDiagram\nnamespace Shop {\nclass Order",
+			line: 2,
+		},
+		"brace without block": {
+			src:  "classDiagram\nA --> B\n}",
+			line: 3,
+		},
+		"invalid class declaration": {
+			src:  "classDiagram\nclass Order Item",
+			line: 2,
+		},
+		"namespace without brace": {
+			src:  "classDiagram\nnamespace Shop",
+			line: 2,
+		},
+		"note without quotes": {
+			src:  "classDiagram\nnote for Order paid once",
+			line: 2,
+		},
+		"invalid direction": {
+			src:  "classDiagram\ndirection UP",
+			line: 2,
+		},
+		"unknown relation": {
+			src:  "classDiagram\nA -> B",
+			line: 2,
+		},
+		"member without name": {
+			src:  "classDiagram\nOrder :",
+			line: 2,
+		},
+		"relation without target": {
+			src:  "classDiagram\nA <|--",
+			line: 2,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSyntax(t, Validate(tc.src), tc.line)
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package mermaid
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shape is a node bracket pair, openers sharing a prefix go longest first
+type shape struct {
+	open  string
+	close []string
+}
+
+var shapes = []shape{
+	{"(((", []string{")))"}},
+	{"((", []string{"))"}},
+	{"([", []string{"])"}},
+	{"[[", []string{"]]"}},
+	{"[(", []string{")]"}},
+	{"{{", []string{"}}"}},
+	{"[/", []string{"/]", `\]`}},
+	{`[\`, []string{`\]`, "/]"}},
+	{"[", []string{"]"}},
+	{"(", []string{")"}},
+	{"{", []string{"}"}},
+	{">", []string{"]"}},
+}
+
+var (
+	// -- text -->, == text ==>, -. text .->
+	textLinkPattern = regexp.MustCompile(`^<?(?:--|==|-\.)\s+\S.*?\s*(?:-{2,}[>ox]?|={2,}[>ox]?|\.-+[>ox]?)`)
+	linkPattern     = regexp.MustCompile(`^(?:[<ox]?(?:-{2,}[>ox]|-{3,}|={2,}[>ox]|={3,}|-\.+-[>ox]?)|~{3,})`)
+)
+
+const labelSpecialChars = `[](){}"`
+
+func validateFlowchart(lines []line) error {
+	var (
+		stack []block
+		err   error
+	)
+
+	for _, l := range lines {
+		for _, text := range splitStatements(l.text) {
+			switch kw := keyword(text); kw {
+			case "subgraph":
+				if strings.TrimSpace(strings.TrimPrefix(text, kw)) == "" {
+					return l.errorf("subgraph needs an id or a title")
+				}
+				stack = append(stack, block{kind: kw, line: l})
+			case "end":
+				if text != kw {
+					return l.errorf("unexpected %q after end", strings.TrimSpace(strings.TrimPrefix(text, kw)))
+				}
+				if stack, err = closeBlock(stack, l, "subgraph"); err != nil {
+					return err
+				}
+			case "direction":
+				if !directions[strings.TrimSpace(strings.TrimPrefix(text, kw))] {
+					return l.errorf("invalid direction %q, expected TB, TD, BT, RL or LR", text)
+				}
+			case "classDef", "class", "style", "linkStyle", "click":
+				if len(strings.Fields(text)) < 3 && !(kw == "click" && len(strings.Fields(text)) == 2) {
+					return l.errorf("%s needs a target and a value", kw)
+				}
+			default:
+				if err := validateChain(l, text); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return unclosed(stack)
+}
+
+// splitStatements splits a line by ";" outside of quotes and node brackets
+func splitStatements(text string) []string {
+	var (
+		result []string
+		depth  int
+		quoted bool
+		start  int
+	)
+	for i, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case strings.ContainsRune("[({", r):
+			depth++
+		case strings.ContainsRune("])}", r) && depth > 0:
+			depth--
+		case r == ';' && depth == 0:
+			if s := strings.TrimSpace(text[start:i]); s != "" {
+				result = append(result, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.TrimSpace(text[start:]); s != "" {
+		result = append(result, s)
+	}
+	return result
+}
+
+// chain is a statement like `A[Start] --> B{Check} -->|yes| C & D`
+type chain struct {
+	s   string
+	pos int
+	l   line
+}
+
+func validateChain(l line, text string) error {
+	c := &chain{s: text, l: l}
+	if err := c.nodes(); err != nil {
+		return err
+	}
+	for {
+		c.skipSpaces()
+		if c.pos == len(c.s) {
+			return nil
+		}
+		if err := c.link(); err != nil {
+			return err
+		}
+		c.skipSpaces()
+		if err := c.nodes(); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *chain) rest() string {
+	return c.s[c.pos:]
+}
+
+func (c *chain) skipSpaces() {
+	for c.pos < len(c.s) && (c.s[c.pos] == ' ' || c.s[c.pos] == '\t') {
+		c.pos++
+	}
+}
+
+func (c *chain) nodes() error {
+	for {
+		if err := c.node(); err != nil {
+			return err
+		}
+		c.skipSpaces()
+		if !strings.HasPrefix(c.rest(), "&") {
+			return nil
+		}
+		c.pos++
+		c.skipSpaces()
+	}
+}
+
+func (c *chain) node() error {
+	id := c.word(true)
+	if id == "" {
+		if c.pos == len(c.s) {
+			return c.l.errorf("expected node after %q", c.s)
+		}
+		return c.l.errorf("expected node id at %q", c.rest())
+	}
+	if id == "end" {
+		return c.l.errorf(`node id "end" is reserved, use another id`)
+	}
+
+	if err := c.shape(); err != nil {
+		return err
+	}
+	if strings.HasPrefix(c.rest(), ":::") {
+		c.pos += len(":::")
+		if c.word(false) == "" {
+			return c.l.errorf("expected class name after ::: in %q", c.s)
+		}
+	}
+	return nil
+}
+
+// word reads an identifier, single hyphens are allowed inside node ids
+func (c *chain) word(hyphens bool) string {
+	start := c.pos
+	for c.pos < len(c.s) {
+		r, size := utf8.DecodeRuneInString(c.rest())
+		if r == '-' && hyphens && c.pos > start && c.pos+1 < len(c.s) {
+			next, _ := utf8.DecodeRuneInString(c.s[c.pos+1:])
+			if isIDRune(next) {
+				c.pos += size
+				continue
+			}
+		}
+		if !isIDRune(r) {
+			break
+		}
+		c.pos += size
+	}
+	return c.s[start:c.pos]
+}
+
+func isIDRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func (c *chain) shape() error {
+	rest := c.rest()
+	for _, sh := range shapes {
+		if !strings.HasPrefix(rest, sh.open) {
+			continue
+		}
+		body := rest[len(sh.open):]
+
+		if strings.HasPrefix(body, `"`) {
+			end := strings.Index(body[1:], `"`)
+			if end < 0 {
+				return c.l.errorf("unclosed quote in node label %q", rest)
+			}
+			after := body[end+2:]
+			for _, closer := range sh.close {
+				if strings.HasPrefix(after, closer) {
+					c.pos += len(sh.open) + end + 2 + len(closer)
+					return nil
+				}
+			}
+			return c.l.errorf("node label %q must be closed with %q", rest, sh.close[0])
+		}
+
+		end := -1
+		var closer string
+		for _, cl := range sh.close {
+			if i := strings.Index(body, cl); i >= 0 && (end < 0 || i < end) {
+				end, closer = i, cl
+			}
+		}
+		if end < 0 {
+			return c.l.errorf("node label %q is not closed with %q", rest, sh.close[0])
+		}
+		label := body[:end]
+		if strings.TrimSpace(label) == "" {
+			return c.l.errorf("empty node label in %q", c.s)
+		}
+		if i := strings.IndexAny(label, labelSpecialChars); i >= 0 {
+			return c.l.errorf("node label %q contains %q, wrap the label in double quotes", label, label[i])
+		}
+		c.pos += len(sh.open) + end + len(closer)
+		return nil
+	}
+	return nil
+}
+
+func (c *chain) link() error {
+	rest := c.rest()
+	arrow := textLinkPattern.FindString(rest)
+	if arrow == "" {
+		arrow = linkPattern.FindString(rest)
+	}
+	if arrow == "" {
+		return c.l.errorf("expected link like --> or --- at %q", rest)
+	}
+
+	// o and x heads are ambiguous with ids starting with these letters
+	if last := arrow[len(arrow)-1]; (last == 'o' || last == 'x') && len(arrow) < len(rest) {
+		if r, _ := utf8.DecodeRuneInString(rest[len(arrow):]); isIDRune(r) {
+			arrow = arrow[:len(arrow)-1]
+		}
+	}
+	c.pos += len(arrow)
+
+	c.skipSpaces()
+	if strings.HasPrefix(c.rest(), "|") {
+		end := strings.Index(c.rest()[1:], "|")
+		if end < 0 {
+			return c.l.errorf("link text %q is not closed with |", c.rest())
+		}
+		c.pos += end + 2
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package mermaid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateFlowchart(t *testing.T) {
+	tests := map[string]struct {
+		src string
+		// line of the expected syntax error, 0 for valid diagrams
+		line int
+	}{
+		"chain with shapes": {
+			src: "flowchart LR\nA[Start] --> B{Check} --> C((Done))",
+		},
+		"graph header without direction": {
+			src: "graph\nA --> B",
+		},
+		"edge labels": {
+			src: "flowchart TD\nA -->|yes| B\nA -- no --> C\nC == retry ==> A\nB -. async .-> D",
+		},
+		"link kinds": {
+			src: "flowchart LR\nA --- B\nB ==> C\nC -.-> D\nD --o E\nE --x F\nF <--> G\nG ~~~ H",
+		},
+		"x and o heads before ids": {
+			src: "flowchart LR\nA --xray\nA --oscar",
+		},
+		"ampersand and statements": {
+			src: "flowchart LR\nA & B --> C & D; C --> E",
+		},
+		"quoted labels with special chars": {
+			src: "flowchart LR\nA[\"f(x) = {1}\"] --> B(\"[list]\")",
+		},
+		"hyphenated ids and classes": {
+			src: "flowchart LR\nweb-app:::frontend --> api_v2\nclassDef frontend fill:#f9f\nclass api_v2 frontend",
+		},
+		"nested subgraphs": {
+			src: "flowchart TB\nsubgraph cloud [Cloud]\ndirection LR\nsubgraph vpc\nA --> B\nend\nend\ncloud --> C",
+		},
+		"comments and front matter": {
+			src: "---\ntitle: Flow\n---\n%% comment\nflowchart LR\n\n%%{init: {}}%%\nA --> B\n",
+		},
+		"non-ASCII ids and labels": {
+			src: "flowchart LR\nКлиент[Клиент] --> Сервер",
+		},
+		"comment after a statement": {
+			src:  "flowchart LR\nA --> B %% comments take whole lines",
+			line: 2,
+		},
+		"empty diagram": {
+			src:  "%% only a comment",
+			line: 1,
+		},
+		"invalid direction": {
+			src:  "flowchart XY\nA --> B",
+			line: 1,
+		},
+		"unsupported diagram": {
+			src:  "pie\n\"a\" : 1",
+			line: 1,
+		},
+		"unclosed front matter": {
+			src:  "---\ntitle: Flow\nflowchart LR",
+			line: 1,
+		},
+		"unclosed subgraph": {
+			src:  "flowchart LR\nsubgraph one\nA --> B",
+			line: 2,
+		},
+		"end without subgraph": {
+			src:  "flowchart LR\nA --> B\nend",
+			line: 3,
+		},
+		"subgraph without title": {
+			src:  "flowchart LR\nsubgraph\nend",
+			line: 2,
+		},
+		"reserved end id": {
+			src:  "flowchart LR\nA --> end",
+			line: 2,
+		},
+		"missing target": {
+			src:  "flowchart LR\nA -->",
+			line: 2,
+		},
+		"unknown link": {
+			src:  "flowchart LR\nA -> B",
+			line: 2,
+		},
+		"unclosed link text": {
+			src:  "flowchart LR\nA -->|yes B",
+			line: 2,
+		},
+		"unclosed node label": {
+			src:  "flowchart LR\nA[Start --> B",
+			line: 2,
+		},
+		"special chars in unquoted label": {
+			src:  "flowchart LR\nA[f(x)] --> B",
+			line: 2,
+		},
+		"empty label": {
+			src:  "flowchart LR\nA[ ] --> B",
+			line: 2,
+		},
+		"missing class name": {
+			src:  "flowchart LR\nA::: --> B",
+			line: 2,
+		},
+		"style without value": {
+			src:  "flowchart LR\nA --> B\nstyle A",
+			line: 3,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSyntax(t, Validate(tc.src), tc.line)
+		})
+	}
+}
+
+// assertSyntax checks err is nil for line 0 or a SyntaxError at line
+func assertSyntax(t *testing.T, err error, line int) {
+	t.Helper()
+	if line == 0 {
+		if err != nil {
+			t.Fatalf("Validate = %v, want nil", err)
+		}
+		return
+	}
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Validate = %v, want a syntax error at line %d", err, line)
+	}
+	if syntaxErr.Line != line {
+		t.Fatalf("Validate = %v, want it at line %d", err, line)
+	}
+}
@@ -0,0 +1,123 @@
+package mermaid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError points to the first invalid line of a diagram
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type line struct {
+	num  int
+	text string
+}
+
+func (l line) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: l.num, Msg: fmt.Sprintf(format, args...)}
+}
+
+// block is an opened subgraph, sequence section or class body waiting for its end
+type block struct {
+	kind string
+	line line
+}
+
+var directions = map[string]bool{"TB": true, "TD": true, "BT": true, "RL": true, "LR": true}
+
+// Validate checks syntax of flowchart, sequence and class diagrams. It covers
+// node and edge grammar and block nesting, not styling or rendering options
+func Validate(src string) error {
+	lines, err := significantLines(src)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return &SyntaxError{Line: 1, Msg: "diagram is empty"}
+	}
+
+	header := lines[0]
+	fields := strings.Fields(header.text)
+	switch fields[0] {
+	case "flowchart", "graph":
+		if len(fields) > 2 || (len(fields) == 2 && !directions[strings.TrimSuffix(fields[1], ";")]) {
+			return header.errorf("invalid flowchart header %q, expected direction TB, TD, BT, RL or LR", header.text)
+		}
+		return validateFlowchart(lines[1:])
+	case "sequenceDiagram":
+		if len(fields) > 1 {
+			return header.errorf("unexpected %q after sequenceDiagram", strings.Join(fields[1:], " "))
+		}
+		return validateSequence(lines[1:])
+	case "classDiagram", "classDiagram-v2":
+		if len(fields) > 1 {
+			return header.errorf("unexpected %q after classDiagram", strings.Join(fields[1:], " "))
+		}
+		return validateClass(lines[1:])
+	default:
+		return header.errorf("unsupported diagram type %q, expected flowchart, sequenceDiagram or classDiagram", fields[0])
+	}
+}
+
+// significantLines drops blank lines, %% comments, init directives and front matter
+func significantLines(src string) ([]line, error) {
+	var (
+		result      []line
+		frontMatter *line
+	)
+
+	for i, text := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		l := line{num: i + 1, text: strings.TrimSpace(text)}
+		switch {
+		case frontMatter != nil:
+			if l.text == "---" {
+				frontMatter = nil
+			}
+		case l.text == "---" && len(result) == 0:
+			frontMatter = &l
+		case l.text == "" || strings.HasPrefix(l.text, "%%"):
+		default:
+			result = append(result, l)
+		}
+	}
+
+	if frontMatter != nil {
+		return nil, frontMatter.errorf("front matter is not closed with ---")
+	}
+	return result, nil
+}
+
+// closeBlock pops the innermost block, ends must match the kinds in want
+func closeBlock(stack []block, l line, want ...string) ([]block, error) {
+	if len(stack) == 0 {
+		return nil, l.errorf("%q without opening block", l.text)
+	}
+	top := stack[len(stack)-1]
+	for _, kind := range want {
+		if top.kind == kind {
+			return stack[:len(stack)-1], nil
+		}
+	}
+	return nil, l.errorf("%q closes %q opened at line %d", l.text, top.kind, top.line.num)
+}
+
+func unclosed(stack []block) error {
+	if len(stack) == 0 {
+		return nil
+	}
+	top := stack[len(stack)-1]
+	return top.line.errorf("%q is not closed", top.kind)
+}
+
+// keyword returns the first word of a statement, "title:" like forms included
+func keyword(text string) string {
+	word, _, _ := strings.Cut(text, " ")
+	return strings.TrimSuffix(word, ":")
+}
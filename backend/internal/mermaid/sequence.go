@@ -0,0 +1,73 @@
+package mermaid
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	actorName = `[^:<>=,;+\-\s](?:[^:<>=,;\-]*[^:<>=,;\-\s])?`
+	arrows    = `<<-->>|<<->>|-->>|->>|-->|->|--x|-x|--\)|-\)`
+)
+
+var (
+	messagePattern     = regexp.MustCompile(`^(` + actorName + `)\s*(` + arrows + `)\s*[+-]?\s*(` + actorName + `)\s*:`)
+	notePattern        = regexp.MustCompile(`(?i)^note\s+(left of|right of|over)\s+` + actorName + `(\s*,\s*` + actorName + `)?\s*:`)
+	participantPattern = regexp.MustCompile(`^(?:create\s+)?(?:participant|actor)\s+` + actorName + `(\s+as\s+\S.*)?$`)
+)
+
+// sections which may be split by else/and/option
+var sectionSplits = map[string]string{
+	"else":   "alt",
+	"and":    "par",
+	"option": "critical",
+}
+
+func validateSequence(lines []line) error {
+	var (
+		stack []block
+		err   error
+	)
+
+	for _, l := range lines {
+		// lowercasing may change the byte length, arguments are cut by the raw keyword
+		raw := keyword(l.text)
+		kw := strings.ToLower(raw)
+		args := strings.TrimSpace(l.text[len(raw):])
+
+		switch kw {
+		case "loop", "alt", "opt", "par", "critical", "break", "rect", "box":
+			stack = append(stack, block{kind: kw, line: l})
+		case "else", "and", "option":
+			want := sectionSplits[kw]
+			if len(stack) == 0 || stack[len(stack)-1].kind != want {
+				return l.errorf("%q is only allowed inside %s", kw, want)
+			}
+		case "end":
+			if args != "" {
+				return l.errorf("unexpected %q after end", args)
+			}
+			if stack, err = closeBlock(stack, l, "loop", "alt", "opt", "par", "critical", "break", "rect", "box"); err != nil {
+				return err
+			}
+		case "participant", "actor", "create":
+			if !participantPattern.MatchString(l.text) {
+				return l.errorf("invalid participant %q, expected `participant Name` or `participant Id as Alias`", l.text)
+			}
+		case "note":
+			if !notePattern.MatchString(l.text) {
+				return l.errorf("invalid note %q, expected `Note left of|right of|over A[,B]: text`", l.text)
+			}
+		case "activate", "deactivate", "destroy":
+			if args == "" {
+				return l.errorf("%s needs a participant", kw)
+			}
+		case "autonumber", "title", "acctitle", "accdescr", "links", "link", "properties", "details":
+		default:
+			if !messagePattern.MatchString(l.text) {
+				return l.errorf("invalid message %q, expected `A->>B: text`", l.text)
+			}
+		}
+	}
+	return unclosed(stack)
+}
@@ -0,0 +1,28 @@
+package mermaid
+
+import "testing"
+
+func TestValidateSequenceNonASCII(t *testing.T) {
+	valid := []string{
+		"sequenceDiagram\nКлиент->>Сервер: запрос",
+		"sequenceDiagram\nparticipant Ⱥ\nȺ->>B: привет",
+		"sequenceDiagram\nloop Каждую минуту\nA->>B: ping\nend",
+	}
+	for _, src := range valid {
+		if err := Validate(src); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", src, err)
+		}
+	}
+
+	invalid := []string{
+		"sequenceDiagram\nȺ",
+		"sequenceDiagram\nȺȺȺ x",
+		"sequenceDiagram\nİ",
+		"sequenceDiagram\nend Ⱥ",
+	}
+	for _, src := range invalid {
+		if err := Validate(src); err == nil {
+			t.Errorf("Validate(%q) = nil, want error", src)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -48,6 +49,169 @@ var (
 		},
 		[]string{"status", "file_format"},
 	)
+
+	llmTimeToFirstToken = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_time_to_first_token_seconds",
+			Help:      "Time from model request to the first streamed token",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300},
+		},
+		[]string{"file_format", "model"},
+	)
+
+	llmTokensPerSecond = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_tokens_per_second",
+			Help:      "Completion tokens generation speed",
+			Buckets:   []float64{1, 2, 5, 10, 20, 30, 50, 75, 100, 150, 200, 300},
+		},
+		[]string{"file_format", "model"},
+	)
+
+	llmTokens = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_tokens",
+			Help:      "Number of prompt and completion tokens per model request",
+			Buckets:   prometheus.ExponentialBuckets(16, 2, 12),
+		},
+		[]string{"type", "file_format", "model"},
+	)
+
+	llmBackendRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_backend_requests_total",
+			Help:      "Number of model requests per backend",
+		},
+		[]string{"backend", "status"},
+	)
+
+	llmBackendOutstanding = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "llm_backend_outstanding_requests",
+			Help:      "Number of running model requests per backend",
+		},
+		[]string{"backend"},
+	)
+
+	llmBackendHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "llm_backend_healthy",
+			Help:      "Whether backend receives requests, 0 for ejected backends",
+		},
+		[]string{"backend"},
+	)
+
+	llmBackendCircuit = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "llm_backend_circuit_state",
+			Help:      "Circuit breaker state per backend: 0 closed, 1 open, 2 half-open",
+		},
+		[]string{"backend"},
+	)
+
+	llmRetriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_retries_total",
+			Help:      "Number of retried model calls by the error of the failed attempt",
+		},
+		[]string{"backend", "reason"},
+	)
+
+	cacheRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Number of cache lookups by tier and result",
+		},
+		[]string{"tier", "result"},
+	)
+
+	cacheMemoryBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_memory_bytes",
+			Help:      "Size of keys and values in the in-memory cache tier",
+		},
+	)
+
+	cacheRemoteAvailable = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_remote_available",
+			Help:      "Whether the redis cache tier is used, 0 while it's skipped after errors",
+		},
+	)
+
+	semanticCacheRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "semantic_cache_requests_total",
+			Help:      "Semantic cache lookups after an exact cache miss",
+		},
+		[]string{"result"},
+	)
+
+	semanticCacheSimilarity = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "semantic_cache_similarity",
+			Help:      "Cosine similarity of the closest cached prompt",
+			Buckets:   []float64{0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.92, 0.94, 0.96, 0.98, 1},
+		},
+	)
+
+	dedupRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dedup_requests_total",
+			Help:      "Number of requests joined to an identical in-flight request",
+		},
+		[]string{"type"},
+	)
+
+	queueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_depth",
+			Help:      "Number of model calls waiting in the queue",
+		},
+		[]string{"priority"},
+	)
+
+	queueRunning = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_running",
+			Help:      "Number of admitted model calls",
+		},
+	)
+
+	queueWaitDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "queue_wait_seconds",
+			Help:      "Time model calls wait in the queue",
+			Buckets:   []float64{0, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+		},
+		[]string{"priority", "result"},
+	)
+
+	llmBackendFreeSlots = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "llm_backend_free_slots",
+			Help:      "Free llama.cpp slots per backend at the last health check",
+		},
+		[]string{"backend"},
+	)
 )
 
 func HttpRequestsTotal(method, path, code string) {
@@ -79,6 +243,132 @@ func FilePreprocessDuration(status, fileFormat string, duration time.Duration) {
 	}).Observe(duration.Seconds())
 }
 
+func LLMTimeToFirstToken(fileFormat, model string, duration time.Duration) {
+	llmTimeToFirstToken.With(prometheus.Labels{
+		"file_format": fileFormat,
+		"model":       model,
+	}).Observe(duration.Seconds())
+}
+
+func LLMTokensPerSecond(fileFormat, model string, tokensPerSecond float64) {
+	llmTokensPerSecond.With(prometheus.Labels{
+		"file_format": fileFormat,
+		"model":       model,
+	}).Observe(tokensPerSecond)
+}
+
+// LLMTokens observes token count of tokenType: "prompt" or "completion"
+func LLMTokens(tokenType, fileFormat, model string, count int64) {
+	llmTokens.With(prometheus.Labels{
+		"type":        tokenType,
+		"file_format": fileFormat,
+		"model":       model,
+	}).Observe(float64(count))
+}
+
+// LLMBackendRequest counts a finished request, status is "success" or "failed"
+func LLMBackendRequest(backend, status string) {
+	llmBackendRequestsTotal.With(prometheus.Labels{
+		"backend": backend,
+		"status":  status,
+	}).Inc()
+}
+
+func LLMBackendOutstanding(backend string, count int64) {
+	llmBackendOutstanding.With(prometheus.Labels{
+		"backend": backend,
+	}).Set(float64(count))
+}
+
+func LLMBackendHealthy(backend string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	llmBackendHealthy.With(prometheus.Labels{
+		"backend": backend,
+	}).Set(value)
+}
+
+func LLMBackendCircuit(backend string, state int) {
+	llmBackendCircuit.With(prometheus.Labels{
+		"backend": backend,
+	}).Set(float64(state))
+}
+
+func LLMRetry(backend, reason string) {
+	llmRetriesTotal.With(prometheus.Labels{
+		"backend": backend,
+		"reason":  reason,
+	}).Inc()
+}
+
+// CacheRequest counts a cache lookup, result is "hit", "miss" or "error"
+func CacheRequest(tier, result string) {
+	cacheRequestsTotal.With(prometheus.Labels{
+		"tier":   tier,
+		"result": result,
+	}).Inc()
+}
+
+func CacheMemoryBytes(size int64) {
+	cacheMemoryBytes.Set(float64(size))
+}
+
+func CacheRemoteAvailable(available bool) {
+	value := 0.0
+	if available {
+		value = 1
+	}
+	cacheRemoteAvailable.Set(value)
+}
+
+// SemanticCacheRequest counts a semantic lookup, result is "hit", "miss" or "error"
+func SemanticCacheRequest(result string) {
+	semanticCacheRequestsTotal.With(prometheus.Labels{
+		"result": result,
+	}).Inc()
+}
+
+func SemanticCacheSimilarity(similarity float64) {
+	semanticCacheSimilarity.Observe(similarity)
+}
+
+// DedupRequest counts a request served by an identical in-flight one, type is "send" or "stream"
+func DedupRequest(requestType string) {
+	dedupRequestsTotal.With(prometheus.Labels{
+		"type": requestType,
+	}).Inc()
+}
+
+func QueueDepth(priority string, depth int) {
+	queueDepth.With(prometheus.Labels{
+		"priority": priority,
+	}).Set(float64(depth))
+}
+
+func QueueRunning(running int) {
+	queueRunning.Set(float64(running))
+}
+
+// QueueWait observes the queue wait, result is "admitted", "rejected", "timeout" or "cancelled"
+func QueueWait(priority, result string, wait time.Duration) {
+	queueWaitDuration.With(prometheus.Labels{
+		"priority": priority,
+		"result":   result,
+	}).Observe(wait.Seconds())
+}
+
+func LLMBackendFreeSlots(backend string, slots int64) {
+	llmBackendFreeSlots.With(prometheus.Labels{
+		"backend": backend,
+	}).Set(float64(slots))
+}
+
+// unmatchedRoute labels requests no route matched
+const unmatchedRoute = "unmatched"
+
+// Middleware labels requests by the route pattern, so "/jobs/{id}" is one series for all job ids
 func Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -87,11 +377,24 @@ func Middleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 
 		duration := time.Since(start)
-		HttpRequestsTotal(r.Method, r.URL.Path, http.StatusText(ww.status))
-		HttpRequestDuration(r.Method, r.URL.Path, duration)
+		route := routePattern(r)
+		HttpRequestsTotal(r.Method, route, http.StatusText(ww.status))
+		HttpRequestDuration(r.Method, route, duration)
 	})
 }
 
+// routePattern is complete only after the router has served the request
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return unmatchedRoute
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	return unmatchedRoute
+}
+
 type statusResponseWriter struct {
 	http.ResponseWriter
 	status int
@@ -0,0 +1,34 @@
+package models
+
+import "fmt"
+
+// BatchExplainRequest represents request for batch explain endpoints
+type BatchExplainRequest struct {
+	Items []ExplainRequest `json:"items"`
+
+	// Optional number of items processed in parallel, capped by server config
+	Concurrency *int `json:"concurrency" example:"2"`
+}
+
+func (r BatchExplainRequest) Validate() error {
+	if len(r.Items) == 0 {
+		return fmt.Errorf("items are empty")
+	}
+	if r.Concurrency != nil && *r.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	return nil
+}
+
+// BatchItemResult holds result of a single batch item, Index points to the request item
+type BatchItemResult struct {
+	Index    int              `json:"index" example:"0"`
+	FileName string           `json:"file_name" example:"diagram.png"`
+	Result   *ExplainResponse `json:"result,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+type BatchExplainResponse struct {
+	Items  []BatchItemResult `json:"items"`
+	Failed int               `json:"failed"`
+}
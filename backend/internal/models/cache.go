@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CacheEntry is a cache entry of some kind, "explain" entries are cached answers. RequestHash is the last
+// hash of the key, the settings hash for other kinds. Model, prompt version and the answer are set for
+// answers only, answers cached before models and prompt versions were recorded have them empty.
+// Prompts lists prompts indexed by "semantic" entries, Pages is the number of pages rendered by "preprocess" entries
+type CacheEntry struct {
+	Key           string                 `json:"key" example:"diagram-ai:explain:v1:9f86d081884c7d65:2c26b46b68ffc68f"`
+	Kind          string                 `json:"kind" example:"explain"`
+	CacheVersion  string                 `json:"cache_version" example:"v1"`
+	FileHash      string                 `json:"file_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	RequestHash   string                 `json:"request_hash" example:"2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"`
+	Model         string                 `json:"model,omitempty" example:"qwen2.5-vl-7b"`
+	PromptVersion string                 `json:"prompt_version,omitempty" example:"1"`
+	CreatedAt     *time.Time             `json:"created_at,omitempty"`
+	Size          int                    `json:"size" example:"2048"`
+	Explanation   string                 `json:"explanation"`
+	Structured    *StructuredExplanation `json:"structured,omitempty"`
+	Prompts       []string               `json:"prompts,omitempty"`
+	Pages         int                    `json:"pages,omitempty" example:"1"`
+}
+
+// CachePurgeRequest selects cache entries to delete, all given filters must match.
+// Prefix is matched against the key after "<namespace>:<kind>:", e.g. "v1:" or "v1:<file hash>".
+// Only answers record models and prompt versions, other kinds never match these filters
+type CachePurgeRequest struct {
+	Prefix        string `json:"prefix,omitempty" example:"v1:"`
+	Kind          string `json:"kind,omitempty" example:"explain"`
+	Model         string `json:"model,omitempty" example:"qwen2.5-vl-7b"`
+	PromptVersion string `json:"prompt_version,omitempty" example:"1"`
+	CacheVersion  string `json:"cache_version,omitempty" example:"v1"`
+}
+
+func (r CachePurgeRequest) Validate() error {
+	if r.Prefix == "" && r.Kind == "" && r.Model == "" && r.PromptVersion == "" && r.CacheVersion == "" {
+		return fmt.Errorf("at least one of prefix, kind, model, prompt_version or cache_version is required")
+	}
+	return nil
+}
+
+type CachePurgeResponse struct {
+	Deleted int `json:"deleted" example:"12"`
+}
+
+// CacheStats describes cache entries of every cache tier and lookups since the backend start
+type CacheStats struct {
+	Tiers    []CacheTierStats `json:"tiers"`
+	Hits     int64            `json:"hits" example:"30"`
+	Misses   int64            `json:"misses" example:"70"`
+	HitRatio float64          `json:"hit_ratio" example:"0.3"`
+}
+
+type CacheTierStats struct {
+	Tier    string `json:"tier" example:"redis"`
+	Entries int64  `json:"entries" example:"120"`
+	Bytes   int64  `json:"bytes" example:"524288"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "fmt"
+
+const (
+	TargetMermaid  = "mermaid"
+	TargetPlantUML = "plantuml"
+)
+
+// ConvertRequest represents request for diagram conversion endpoint
+type ConvertRequest struct {
+	ExplainRequest
+
+	// Target diagram language
+	Target string `json:"target" enums:"mermaid,plantuml" example:"mermaid"`
+}
+
+func (r ConvertRequest) Validate() error {
+	if err := r.ExplainRequest.Validate(); err != nil {
+		return err
+	}
+	switch r.Target {
+	case TargetMermaid, TargetPlantUML:
+		return nil
+	case "":
+		return fmt.Errorf("target is empty")
+	default:
+		return fmt.Errorf("unsupported target %q", r.Target)
+	}
+}
+
+type ConvertResponse struct {
+	Target string `json:"target" example:"mermaid"`
+	Source string `json:"source" example:"flowchart LR\n  A[Client] --> B[API]"`
+
+	// Number of model calls, answers failing validation are sent back with the syntax error.
+	// Zero when the uploaded text already was valid target code
+	Attempts int `json:"attempts" example:"1"`
+
+	// Model that wrote the code, empty when no model was called
+	Model string `json:"model,omitempty" example:"qwen2.5-7b"`
+	FormatInfo
+}
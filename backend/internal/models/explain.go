@@ -1,31 +1,96 @@
 package models
 
-import "fmt"
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
 
 // ExplainRequest represents request for explain endpoint
 type ExplainRequest struct {
 	Prompt     string `json:"prompt" example:"Explain architecture"`
 	FileBase64 string `json:"file_base64" validate:"required" example:"iVBORw0KGgoAAAANSUhEUgAA..."`
 	FileName   string `json:"file_name" validate:"required" example:"diagram.png"`
-	FileFormat string `json:"file_format" validate:"required" example:"png"`
+	FileFormat string `json:"file_format" example:"png"`
 
 	// Optional generation parameters
 	Generation *GenerationParams `json:"generation"`
+
+	// Optional output mode, "structured" returns a typed explanation
+	Output string `json:"output" enums:"text,structured" example:"text"`
+
+	// FileData holds decoded FileBase64 once File was called
+	FileData []byte `json:"-"`
+
+	fileHash string
 }
 
 func (r ExplainRequest) Validate() error {
-	if r.FileBase64 == "" {
+	if r.FileBase64 == "" && len(r.FileData) == 0 {
 		return fmt.Errorf("file_base64 is empty")
 	}
 	if r.FileName == "" {
 		return fmt.Errorf("file_name is empty")
 	}
-	if r.FileFormat == "" {
-		return fmt.Errorf("file_format is empty")
+	if r.Output != "" && r.Output != OutputText && r.Output != OutputStructured {
+		return fmt.Errorf("output must be %q or %q", OutputText, OutputStructured)
 	}
 	return nil
 }
 
+func (r ExplainRequest) Structured() bool {
+	return r.Output == OutputStructured
+}
+
+// File returns raw file content, base64 payload is decoded once and kept in FileData
+func (r *ExplainRequest) File() ([]byte, error) {
+	if r.FileData != nil {
+		return r.FileData, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(r.FileBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64: %w", err)
+	}
+	r.FileData = data
+	return data, nil
+}
+
+// FileReader reads raw file content, base64 payload is decoded while reading
+func (r *ExplainRequest) FileReader() io.Reader {
+	if r.FileData != nil {
+		return bytes.NewReader(r.FileData)
+	}
+	return base64.NewDecoder(base64.StdEncoding, strings.NewReader(r.FileBase64))
+}
+
+// FileHash returns hex sha256 of raw file content, or of the payload if it isn't valid base64
+func (r *ExplainRequest) FileHash() string {
+	if r.fileHash != "" {
+		return r.fileHash
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r.FileReader()); err != nil {
+		h.Reset()
+		io.WriteString(h, r.FileBase64)
+	}
+	r.fileHash = hex.EncodeToString(h.Sum(nil))
+	return r.fileHash
+}
+
+// FileAsBase64 returns file content as base64, encoding FileData when there is no payload
+func (r *ExplainRequest) FileAsBase64() string {
+	if r.FileBase64 != "" {
+		return r.FileBase64
+	}
+	return base64.StdEncoding.EncodeToString(r.FileData)
+}
+
 // GenerationParams holds optional OpenAI-like generation parameters
 type GenerationParams struct {
 	Temperature *float64 `json:"temperature" example:"0.7" default:"0.7"`
@@ -33,11 +98,89 @@ type GenerationParams struct {
 }
 
 type ExplainResponse struct {
-	Explanation string `json:"explanation"`
+	Explanation string                 `json:"explanation"`
+	Structured  *StructuredExplanation `json:"structured,omitempty"`
+
+	// Model, Usage and Latency of the model call, absent for cached answers.
+	// Similarity is set when the answer was cached for a similar prompt
+	Cached     bool     `json:"cached,omitempty" example:"false"`
+	Similarity float64  `json:"similarity,omitempty" example:"0.95"`
+	Model      string   `json:"model,omitempty" example:"minicpm-v"`
+	Usage      *Usage   `json:"usage,omitempty"`
+	Latency    *Latency `json:"latency,omitempty"`
+	FormatInfo
+}
+
+// FormatInfo reports the file format detected from content. FileFormat of the request
+// is optional, if it's set and doesn't match the content a warning is returned
+type FormatInfo struct {
+	DeclaredFormat string `json:"declared_format,omitempty" example:"jpg"`
+	DetectedFormat string `json:"detected_format,omitempty" example:"png"`
+	FormatWarning  string `json:"format_warning,omitempty"`
+}
+
+// StreamMeta is sent once as "meta" event before the tokens.
+// Cached answers are replayed as deltas without progress stages
+type StreamMeta struct {
+	Cached     bool    `json:"cached" example:"false"`
+	Similarity float64 `json:"similarity,omitempty" example:"0.95"`
+	FormatInfo
 }
 
 type StreamChunk struct {
-	Delta string `json:"delta,omitempty"`
-	Err   error  `json:"-"`
-	Done  bool   `json:"-"`
+	Delta      string                 `json:"delta,omitempty"`
+	Structured *StructuredExplanation `json:"structured,omitempty"`
+	Meta       *StreamMeta            `json:"-"`
+	Stage      *StreamStage           `json:"-"`
+	Err        error                  `json:"-"`
+	Done       bool                   `json:"-"`
+}
+
+const (
+	StageQueued        = "queued"
+	StagePreprocessing = "preprocessing"
+	StagePreprocessed  = "preprocessed"
+	StageGenerating    = "generating"
+	StageUsage         = "usage"
+)
+
+// StreamStage is a progress event, sent as SSE event named by the stage.
+// Model is set for the generating and usage stages, Position for queued stages
+// sent while the model call waits for a free slot
+type StreamStage struct {
+	Name     string `json:"-"`
+	Model    string `json:"model,omitempty"`
+	Position int    `json:"position,omitempty"`
+	*PreprocessInfo
+	*Usage
+	*Latency
+}
+
+// PreprocessInfo describes model input built from the file, Cached is set when
+// the rendered file was taken from the cache
+type PreprocessInfo struct {
+	Pages  int  `json:"pages" example:"1"`
+	Images int  `json:"images" example:"1"`
+	Cached bool `json:"cached,omitempty" example:"false"`
+}
+
+// Usage holds token counts reported by the model backend
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens" example:"812"`
+	CompletionTokens int64 `json:"completion_tokens" example:"256"`
+	TotalTokens      int64 `json:"total_tokens" example:"1068"`
+}
+
+// Latency of the model call. Time to first token is reported for streams only,
+// tokens per second needs usage from the backend
+type Latency struct {
+	TimeToFirstTokenMs int64   `json:"time_to_first_token_ms,omitempty" example:"850"`
+	TotalMs            int64   `json:"total_ms" example:"5400"`
+	TokensPerSecond    float64 `json:"tokens_per_second,omitempty" example:"56.3"`
+}
+
+// StreamEvent is a buffered stream chunk, ID is sent as SSE id to resume the stream with Last-Event-ID
+type StreamEvent struct {
+	ID    string
+	Chunk StreamChunk
 }
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job represents state of an asynchronous explanation
+type Job struct {
+	ID        string           `json:"id" example:"3f1c9a7e5b2d4c6a8e0f1a2b3c4d5e6f"`
+	Status    JobStatus        `json:"status" example:"running"`
+	Progress  float64          `json:"progress" example:"0.4"`
+	Result    *ExplainResponse `json:"result,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+func (j *Job) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+type JobCreateResponse struct {
+	ID     string    `json:"id" example:"3f1c9a7e5b2d4c6a8e0f1a2b3c4d5e6f"`
+	Status JobStatus `json:"status" example:"queued"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Session keeps a preprocessed diagram and the conversation about it
+type Session struct {
+	ID         string           `json:"id"`
+	FileName   string           `json:"file_name"`
+	FileFormat string           `json:"file_format"`
+	Parts      []SessionPart    `json:"parts"`
+	History    []SessionMessage `json:"history"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// SessionPart is a preprocessed diagram part: prompt text, parsed structure or an image data URL
+type SessionPart struct {
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+type SessionMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type SessionCreateResponse struct {
+	ID        string    `json:"id" example:"3f1c9a7e5b2d4c6a8e0f1a2b3c4d5e6f"`
+	ExpiresAt time.Time `json:"expires_at"`
+	FormatInfo
+}
+
+// SessionMessageRequest represents a follow-up question about the session diagram
+type SessionMessageRequest struct {
+	Content string `json:"content" example:"What happens if PageService fails?"`
+
+	// Optional generation parameters
+	Generation *GenerationParams `json:"generation"`
+}
+
+func (r SessionMessageRequest) Validate() error {
+	if r.Content == "" {
+		return fmt.Errorf("content is empty")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import "fmt"
+
+const (
+	OutputText       = "text"
+	OutputStructured = "structured"
+)
+
+const (
+	DirectionForward       = "forward"
+	DirectionBackward      = "backward"
+	DirectionBidirectional = "bidirectional"
+	DirectionNone          = "none"
+)
+
+// StructuredExplanation is a typed explanation returned in "structured" output mode
+type StructuredExplanation struct {
+	DiagramType   string         `json:"diagram_type" example:"C4 container"`
+	Title         string         `json:"title" example:"Online orders system"`
+	Components    []Component    `json:"components"`
+	Relationships []Relationship `json:"relationships"`
+	Issues        []string       `json:"issues"`
+	Summary       string         `json:"summary" example:"Web app calls backend API which stores orders in PostgreSQL"`
+}
+
+type Component struct {
+	Name        string `json:"name" example:"Backend API"`
+	Type        string `json:"type" example:"service"`
+	Description string `json:"description" example:"Handles business logic"`
+}
+
+type Relationship struct {
+	Source    string `json:"source" example:"Web Frontend"`
+	Target    string `json:"target" example:"Backend API"`
+	Direction string `json:"direction" enums:"forward,backward,bidirectional,none" example:"forward"`
+	Label     string `json:"label" example:"HTTPS / JSON"`
+}
+
+// Validate checks the model answer, the error text is sent back to the model on retry
+func (s *StructuredExplanation) Validate() error {
+	if s.DiagramType == "" {
+		return fmt.Errorf("diagram_type is empty")
+	}
+	if s.Summary == "" {
+		return fmt.Errorf("summary is empty")
+	}
+	for i, c := range s.Components {
+		if c.Name == "" {
+			return fmt.Errorf("components[%d].name is empty", i)
+		}
+	}
+	for i, r := range s.Relationships {
+		if r.Source == "" || r.Target == "" {
+			return fmt.Errorf("relationships[%d] must have source and target", i)
+		}
+		switch r.Direction {
+		case DirectionForward, DirectionBackward, DirectionBidirectional, DirectionNone:
+		default:
+			return fmt.Errorf("relationships[%d].direction %q is not one of forward, backward, bidirectional, none", i, r.Direction)
+		}
+	}
+	return nil
+}
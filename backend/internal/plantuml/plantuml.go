@@ -0,0 +1,173 @@
+package plantuml
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SyntaxError points to the first invalid line of a diagram
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type line struct {
+	num  int
+	text string
+}
+
+func (l line) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: l.num, Msg: fmt.Sprintf(format, args...)}
+}
+
+// block is an opened group, container or free form section waiting for its end
+type block struct {
+	kind string
+	line line
+}
+
+const (
+	blockGroup     = "group"
+	blockBox       = "box"
+	blockContainer = "{"
+	blockClass     = "class body"
+	blockSkinparam = "skinparam"
+	blockNote      = "note"
+	blockLegend    = "legend"
+	blockRef       = "ref"
+	blockTitle     = "title"
+)
+
+// free form blocks are skipped until their closing line
+var blockEnds = map[string]*regexp.Regexp{
+	blockClass:     regexp.MustCompile(`^}`),
+	blockSkinparam: regexp.MustCompile(`^}$`),
+	blockNote:      regexp.MustCompile(`(?i)^end\s*[hr]?note$`),
+	blockLegend:    regexp.MustCompile(`(?i)^end\s*legend$`),
+	blockRef:       regexp.MustCompile(`(?i)^end\s*ref$`),
+	blockTitle:     regexp.MustCompile(`(?i)^end\s*title$`),
+}
+
+// Validate checks syntax of sequence, component and class diagrams between @startuml
+// and @enduml. It covers declarations, arrows and block nesting, not skin parameters
+// or preprocessor logic
+func Validate(src string) error {
+	lines, err := significantLines(src)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return &SyntaxError{Line: 1, Msg: "diagram is empty"}
+	}
+
+	var (
+		body    []line
+		started *line
+	)
+	for _, l := range lines {
+		switch {
+		case started == nil:
+			if !strings.HasPrefix(l.text, "@startuml") {
+				return l.errorf("expected @startuml, got %q", l.text)
+			}
+			started = &l
+			body = body[:0]
+		case strings.HasPrefix(l.text, "@enduml"):
+			if err := validateBody(body, *started); err != nil {
+				return err
+			}
+			started = nil
+		case strings.HasPrefix(l.text, "@start"):
+			return l.errorf("%q inside of diagram started at line %d", l.text, started.num)
+		default:
+			body = append(body, l)
+		}
+	}
+
+	if started != nil {
+		return started.errorf("@startuml is not closed with @enduml")
+	}
+	return nil
+}
+
+// significantLines drops blank lines and ' comments including /' '/ blocks
+func significantLines(src string) ([]line, error) {
+	var (
+		result  []line
+		comment *line
+	)
+
+	for i, text := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		l := line{num: i + 1, text: strings.TrimSpace(text)}
+		if comment != nil {
+			if _, after, ok := strings.Cut(l.text, "'/"); ok {
+				comment = nil
+				l.text = strings.TrimSpace(after)
+			} else {
+				continue
+			}
+		}
+		if before, after, ok := strings.Cut(l.text, "/'"); ok {
+			if _, rest, closed := strings.Cut(after, "'/"); closed {
+				l.text = strings.TrimSpace(before + " " + rest)
+			} else {
+				comment = &l
+				l.text = strings.TrimSpace(before)
+			}
+		}
+		if l.text == "" || strings.HasPrefix(l.text, "'") {
+			continue
+		}
+		result = append(result, l)
+	}
+
+	if comment != nil {
+		return nil, comment.errorf("block comment is not closed with '/")
+	}
+	return result, nil
+}
+
+func validateBody(lines []line, start line) error {
+	var (
+		stack []block
+		err   error
+	)
+
+	for _, l := range lines {
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if end, ok := blockEnds[top.kind]; ok {
+				if end.MatchString(l.text) {
+					stack = stack[:len(stack)-1]
+				}
+				continue
+			}
+		}
+
+		if stack, err = statement(stack, l); err != nil {
+			return err
+		}
+	}
+
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return top.line.errorf("%q is not closed before @enduml", top.line.text)
+	}
+	return nil
+}
+
+func closeBlock(stack []block, l line, kind string) ([]block, error) {
+	if len(stack) == 0 {
+		return nil, l.errorf("%q without opening block", l.text)
+	}
+	top := stack[len(stack)-1]
+	if top.kind != kind {
+		return nil, l.errorf("%q closes %q opened at line %d", l.text, top.line.text, top.line.num)
+	}
+	return stack[:len(stack)-1], nil
+}
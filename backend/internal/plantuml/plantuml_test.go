@@ -0,0 +1,132 @@
+package plantuml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := map[string]string{
+		"sequence": `@startuml
+participant Client
+actor "Оператор" as Op
+Client -> Server : request
+activate Server
+alt success
+  Server --> Client : 200
+else failure
+  Server --> Client : 500
+end
+deactivate Server
+note left of Client : retries
+@enduml`,
+		"sequence non-ASCII": `@startuml
+participant Клиент
+participant Сервер
+Клиент -> Сервер : запрос
+Сервер --> Клиент : ответ
+loop каждую минуту
+  Клиент -> Ȿервис : ping
+end
+@enduml`,
+		"component": `@startuml
+package "Backend" {
+  [API] as api
+  [Cache] #lightblue
+  () HTTP
+}
+api ..> HTTP : serves
+[API] --> [Cache]
+@enduml`,
+		"component non-ASCII": `@startuml
+component Шлюз
+[Платёжный сервис] as pay
+Шлюз --> pay : оплата
+@enduml`,
+		"class": `@startuml
+abstract class Shape {
+  +area() : float
+}
+class Circle
+interface Drawable
+Shape <|-- Circle
+Circle ..|> Drawable
+Order "1" *-- "many" Item : contains
+@enduml`,
+		"class non-ASCII": `@startuml
+class Заказ {
+  сумма : int
+}
+class Позиция
+Заказ "1" *-- "много" Позиция
+Заказ : номер
+@enduml`,
+		"comments": `@startuml
+' line comment
+/' block
+comment '/
+A -> B
+@enduml`,
+	}
+	for name, src := range valid {
+		t.Run(name, func(t *testing.T) {
+			if err := Validate(src); err != nil {
+				t.Errorf("Validate = %v, want nil", err)
+			}
+		})
+	}
+
+	invalid := map[string]struct {
+		src  string
+		line int
+	}{
+		"empty":            {"", 1},
+		"no start":         {"A -> B\n@enduml", 1},
+		"not closed":       {"@startuml\nA -> B", 1},
+		"nested start":     {"@startuml\n@startuml\n@enduml", 2},
+		"unclosed comment": {"@startuml\n/' comment\n@enduml", 2},
+		"unknown":          {"@startuml\nA B C ~~~\n@enduml", 2},
+		"end without alt":  {"@startuml\nA -> B\nend\n@enduml", 3},
+		"unclosed alt":     {"@startuml\nalt ok\nA -> B\n@enduml", 2},
+		"else outside":     {"@startuml\nelse\n@enduml", 2},
+		"end box in alt":   {"@startuml\nalt ok\nend box\n@enduml", 3},
+		"bad note":         {"@startuml\nnote somewhere\n@enduml", 2},
+		"bad ref":          {"@startuml\nref A\n@enduml", 2},
+		"empty activate":   {"@startuml\nactivate\n@enduml", 2},
+		"bad participant":  {"@startuml\nparticipant\n@enduml", 2},
+		"unclosed package": {"@startuml\npackage Backend {\n[API]\n@enduml", 2},
+		"stray brace":      {"@startuml\n}\n@enduml", 2},
+		"non-ASCII junk":   {"@startuml\nКлиент Сервер ~~ запрос\n@enduml", 2},
+		"non-ASCII arrow":  {"@startuml\nКлиент →→ Сервер\n@enduml", 2},
+		"unclosed class":   {"@startuml\nclass Заказ {\nсумма : int\n@enduml", 2},
+	}
+	for name, tc := range invalid {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(tc.src)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Validate = %v, want SyntaxError", err)
+			}
+			if syntaxErr.Line != tc.line {
+				t.Errorf("Validate = %v, want error at line %d", err, tc.line)
+			}
+		})
+	}
+}
+
+// Sequence_Diagram.txt is written for sequencediagram.org, not PlantUML
+func TestValidateBenchmark(t *testing.T) {
+	for _, name := range []string{"Class_Diagram.txt", "С1.txt"} {
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(filepath.Join("../../../benchmark/data/txt", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Validate(string(src)); err != nil {
+				t.Errorf("Validate = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package plantuml
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// \w is ASCII only, identifiers may be in any script
+	name     = `(?:"[^"]+"|[\p{L}\p{N}_.$]+)`
+	endpoint = `(?:\[[^\]]+\]|\(\)\s*` + name + `|:[^:]+:|` + name + `)`
+	label    = `(?:"[^"]*"\s*)?`
+	arrow    = `(?:<<|<\|?|[*o#x}+^/\\])?` +
+		`(?:-+|\.+)(?:\[[^\]]*\])?(?:up|down|left|right|[udlr])?(?:-+|\.+)?` +
+		`(?:>>|\|?>|//|\\\\|[*o#x{+^/\\])?[ox]?`
+)
+
+var (
+	// A -> B : text, [Comp] ..> () Iface, Foo "1" *-- "many" Bar, [-> A, A ->]
+	relationPattern = regexp.MustCompile(`^(?:` + endpoint + `|\[|\?)\s*` + label + arrow + `\s*` + label +
+		`(?:` + endpoint + `|\]|\?)(?:\s*(?:\+\+|--|\*\*|!!))?\s*(?::.*)?$`)
+	memberPattern        = regexp.MustCompile(`^` + name + `\s*:\s*\S`)
+	componentPattern     = regexp.MustCompile(`^\[[^\]]+\](?:\s+as\s+\S+)?(?:\s*<<[^>]*>>)?(?:\s*#\S+)?$`)
+	interfacePattern     = regexp.MustCompile(`^\(\)\s*` + name + `(?:\s+as\s+\S+)?(?:\s*#\S+)?$`)
+	declarationPattern   = regexp.MustCompile(`^` + name + `(?:<[^>]*>)?(?:\s.*)?$`)
+	notePattern          = regexp.MustCompile(`(?i)^[hr]?note\s+(?:(?:left|right|top|bottom|over|across)\b|on\s+link\b|as\s+\S|"[^"]*"\s+as\s+\S)`)
+	separatorPattern     = regexp.MustCompile(`^(?:==.*==|\.\.\..*|\|\|\d*\|\|)$`)
+	directionPattern     = regexp.MustCompile(`(?i)^(?:left to right|top to bottom) direction$`)
+	activationPattern    = regexp.MustCompile(`^\S`)
+	sequenceParticipants = set("participant", "actor", "boundary", "control", "entity", "database", "collections", "queue")
+	containers           = set("package", "node", "folder", "frame", "cloud", "rectangle", "namespace", "together",
+		"component", "card", "storage", "artifact", "file", "agent", "stack", "hexagon", "person", "usecase")
+	classifiers = set("class", "abstract", "interface", "enum", "annotation", "struct", "exception",
+		"protocol", "record", "metaclass", "object", "map", "circle", "diamond")
+	settings = set("title", "header", "footer", "caption", "scale", "hide", "show", "remove", "autonumber",
+		"newpage", "allowmixing", "allow_mixing", "skin", "mainframe", "set", "center", "left", "right",
+		"autoactivate", "delay", "page", "namespaceseparator", "sprite", "return")
+	groups = set("alt", "opt", "loop", "par", "par2", "break", "critical", "group")
+)
+
+func set(words ...string) map[string]bool {
+	result := make(map[string]bool, len(words))
+	for _, w := range words {
+		result[w] = true
+	}
+	return result
+}
+
+// statement validates a single line outside of free form blocks and returns the updated block stack
+func statement(stack []block, l line) ([]block, error) {
+	text := l.text
+	kw, args, _ := strings.Cut(text, " ")
+	kw = strings.ToLower(strings.TrimSuffix(kw, ":"))
+	args = strings.TrimSpace(args)
+	opens := strings.HasSuffix(text, "{")
+
+	switch {
+	case strings.HasPrefix(text, "!"):
+		// preprocessor
+	case text == "}":
+		return closeBlock(stack, l, blockContainer)
+	case separatorPattern.MatchString(text) || directionPattern.MatchString(text):
+	case kw == "skinparam":
+		if opens {
+			return append(stack, block{kind: blockSkinparam, line: l}), nil
+		}
+		if args == "" {
+			return nil, l.errorf("skinparam needs a name and a value")
+		}
+	case kw == "title" && args == "":
+		return append(stack, block{kind: blockTitle, line: l}), nil
+	case kw == "legend":
+		return append(stack, block{kind: blockLegend, line: l}), nil
+	case settings[kw]:
+	case groups[kw]:
+		return append(stack, block{kind: blockGroup, line: l}), nil
+	case kw == "box":
+		return append(stack, block{kind: blockBox, line: l}), nil
+	case kw == "else":
+		if len(stack) == 0 || stack[len(stack)-1].kind != blockGroup {
+			return nil, l.errorf("else is only allowed inside alt, par or group")
+		}
+	case kw == "end":
+		if strings.EqualFold(args, "box") {
+			return closeBlock(stack, l, blockBox)
+		}
+		if args != "" {
+			return nil, l.errorf("unexpected %q, no opened %s block", text, args)
+		}
+		return closeBlock(stack, l, blockGroup)
+	case kw == "note" || kw == "hnote" || kw == "rnote":
+		if !notePattern.MatchString(text) {
+			return nil, l.errorf("invalid note %q, expected `note left of A : text`", text)
+		}
+		if !strings.Contains(text, ":") && !strings.Contains(text, `"`) {
+			return append(stack, block{kind: blockNote, line: l}), nil
+		}
+	case kw == "ref":
+		if !strings.HasPrefix(strings.ToLower(args), "over ") {
+			return nil, l.errorf("invalid ref %q, expected `ref over A, B : text`", text)
+		}
+		if !strings.Contains(text, ":") {
+			return append(stack, block{kind: blockRef, line: l}), nil
+		}
+	case kw == "activate" || kw == "deactivate" || kw == "destroy" || kw == "create":
+		if !activationPattern.MatchString(args) {
+			return nil, l.errorf("%s needs a participant", kw)
+		}
+	case relationPattern.MatchString(text):
+	case sequenceParticipants[kw] || containers[kw] || classifiers[kw]:
+		if kw == "abstract" {
+			args = strings.TrimSpace(strings.TrimPrefix(args, "class"))
+		}
+		if !declarationPattern.MatchString(args) {
+			return nil, l.errorf("invalid %s declaration %q", kw, text)
+		}
+		if opens && classifiers[kw] {
+			return append(stack, block{kind: blockClass, line: l}), nil
+		}
+		if opens {
+			return append(stack, block{kind: blockContainer, line: l}), nil
+		}
+	case componentPattern.MatchString(text) || interfacePattern.MatchString(text) || memberPattern.MatchString(text):
+	default:
+		return nil, l.errorf("unrecognized statement %q, expected declaration or arrow like `A -> B : text`", text)
+	}
+	return stack, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/admission"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+var ErrBatchTooLarge = errors.New("batch is too large")
+
+type explainer interface {
+	Send(ctx context.Context, req *models.ExplainRequest) (*models.ExplainResponse, error)
+}
+
+// BatchService explains many diagrams with bounded concurrency, each item goes
+// through the explain service separately so the cache is reused per item
+type BatchService struct {
+	logger      *log.Logger
+	explainer   explainer
+	concurrency int
+	maxItems    int
+}
+
+func NewBatchService(logger *log.Logger, explainer explainer, cfg config.BatchConfig) *BatchService {
+	return &BatchService{
+		logger:      logger,
+		explainer:   explainer,
+		concurrency: cfg.Concurrency,
+		maxItems:    cfg.MaxItems,
+	}
+}
+
+// Run processes items and calls emit for each item as soon as it finishes.
+// emit calls are serialized, so it may write to a response directly. When ctx is done
+// the items which didn't start are emitted with its error, so every item is emitted once
+func (s *BatchService) Run(ctx context.Context, req *models.BatchExplainRequest, emit func(models.BatchItemResult)) error {
+	if len(req.Items) > s.maxItems {
+		return fmt.Errorf("%w: %d items, max %d", ErrBatchTooLarge, len(req.Items), s.maxItems)
+	}
+
+	concurrency := max(1, s.concurrency)
+	if req.Concurrency != nil {
+		concurrency = min(concurrency, *req.Concurrency)
+	}
+
+	s.logger.Printf("start batch: %d items, concurrency %d\n", len(req.Items), concurrency)
+	ctx = admission.WithPriority(ctx, admission.PriorityBatch)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sema = make(chan struct{}, concurrency)
+	)
+
+	for i := range req.Items {
+		item := &req.Items[i]
+
+		select {
+		case sema <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			for index := i; index < len(req.Items); index++ {
+				emit(models.BatchItemResult{
+					Index:    index,
+					FileName: req.Items[index].FileName,
+					Error:    fmt.Sprintf("service error: %s", context.Cause(ctx)),
+				})
+			}
+			s.logger.Printf("batch stopped: %d of %d items not started\n", len(req.Items)-i, len(req.Items))
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-sema }()
+
+			result := s.explain(ctx, index, item)
+
+			mu.Lock()
+			defer mu.Unlock()
+			emit(result)
+		}(i)
+	}
+
+	wg.Wait()
+	s.logger.Printf("finish batch: %d items\n", len(req.Items))
+	return nil
+}
+
+func (s *BatchService) explain(ctx context.Context, index int, req *models.ExplainRequest) models.BatchItemResult {
+	result := models.BatchItemResult{
+		Index:    index,
+		FileName: req.FileName,
+	}
+
+	if err := req.Validate(); err != nil {
+		result.Error = fmt.Sprintf("request validation failed: %s", err)
+		return result
+	}
+
+	resp, err := s.explainer.Send(ctx, req)
+	if err != nil {
+		result.Error = fmt.Sprintf("service error: %s", err)
+		return result
+	}
+
+	result.Result = resp
+	return result
+}
@@ -2,13 +2,17 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/bpmn"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/drawio"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
 	"github.com/openai/openai-go/v3"
@@ -18,7 +22,10 @@ import (
 	fitz "github.com/gen2brain/go-fitz"
 )
 
-const dpi = 120
+const (
+	dpi         = 120
+	jpegQuality = 85
+)
 
 func getUserPrompt(req *models.ExplainRequest) string {
 	userPrompt := fmt.Sprintf(userPromptTemplate, req.FileName)
@@ -28,13 +35,64 @@ func getUserPrompt(req *models.ExplainRequest) string {
 	return userPrompt
 }
 
-func (e *ExplainService) buildOpenAIReq(req *models.ExplainRequest) (*openai.ChatCompletionNewParams, error) {
+func systemPrompt(req *models.ExplainRequest) string {
+	if req.Structured() {
+		return systemPromptImage + systemPromptStructured
+	}
+	return systemPromptImage
+}
+
+// buildOpenAIReq preprocesses the file into model input, info counts the pages and images it produced
+func (e *ExplainService) buildOpenAIReq(ctx context.Context, req *models.ExplainRequest) (*openai.ChatCompletionNewParams, *models.PreprocessInfo, error) {
+	input, cached, err := e.preprocess(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := &openai.ChatCompletionNewParams{
+		Model:    shared.ChatModel(e.modelName),
+		Messages: buildMessages(req, input),
+	}
+
+	applyGeneration(params, req.Generation)
+
+	info := &models.PreprocessInfo{Pages: input.Pages, Images: len(input.Images), Cached: cached}
+	if req.FileFormat == PDF {
+		info.Pages = info.Images
+	}
+	return params, info, nil
+}
+
+// buildMessages puts the prompts and the preprocessed file together, the extracted text follows the user prompt
+func buildMessages(req *models.ExplainRequest, input *preprocessed) []openai.ChatCompletionMessageParamUnion {
+	userPrompt := getUserPrompt(req)
+	if input.Text != "" {
+		userPrompt = fmt.Sprintf("%s\n%s", userPrompt, input.Text)
+	}
+
+	parts := []openai.ChatCompletionContentPartUnionParam{
+		openai.TextContentPart(userPrompt),
+	}
+	for _, image := range input.Images {
+		parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+			URL: image,
+		}))
+	}
+
+	return []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(systemPrompt(req)),
+		openai.UserMessage(parts),
+	}
+}
+
+// runPreprocess converts the file into model input without the prompts
+func (e *ExplainService) runPreprocess(req *models.ExplainRequest) (*preprocessed, error) {
 	var (
 		duration         time.Duration
 		preprocessStatus string
 
-		messages []openai.ChatCompletionMessageParamUnion
-		err      error
+		input *preprocessed
+		err   error
 	)
 
 	e.logger.Printf("start preprocessing file: %s\n", req.FileName)
@@ -48,23 +106,37 @@ func (e *ExplainService) buildOpenAIReq(req *models.ExplainRequest) (*openai.Cha
 
 	switch req.FileFormat {
 	case PNG, JPEG, JPG:
-		messages = e.buildImageMessages(req)
-	case DRAWIO, BPMN, SVG:
-		messages, err = e.buildDiagramMessages(req)
+		input = preprocessImage(req)
+	case DRAWIO:
+		input, err = e.preprocessDrawio(req)
+		if err != nil {
+			preprocessStatus = "failed"
+			duration = time.Duration(start.Second())
+			return nil, fmt.Errorf("failed to convert drawio: %v", err)
+		}
+	case BPMN:
+		input, err = e.preprocessBpmn(req)
+		if err != nil {
+			preprocessStatus = "failed"
+			duration = time.Duration(start.Second())
+			return nil, fmt.Errorf("failed to convert bpmn: %v", err)
+		}
+	case SVG:
+		input, err = preprocessDiagram(req)
 		if err != nil {
 			preprocessStatus = "failed"
 			duration = time.Duration(start.Second())
 			return nil, fmt.Errorf("failed to convert diagram: %v", err)
 		}
 	case TXT:
-		messages, err = e.buildTxtMessages(req)
+		input, err = preprocessTxt(req)
 		if err != nil {
 			preprocessStatus = "failed"
 			duration = time.Duration(start.Second())
 			return nil, fmt.Errorf("failed to convert txt: %v", err)
 		}
 	case PDF:
-		messages, err = e.buildPdfMessages(req)
+		input, err = preprocessPdf(req)
 		if err != nil {
 			preprocessStatus = "failed"
 			duration = time.Duration(start.Second())
@@ -73,82 +145,122 @@ func (e *ExplainService) buildOpenAIReq(req *models.ExplainRequest) (*openai.Cha
 	default:
 		preprocessStatus = "failed"
 		duration = time.Duration(start.Second())
-		return nil, fmt.Errorf("unsupported fileformat {%s}", req.FileFormat)
+		return nil, checkFormat(req.FileFormat)
 	}
 
-	params := &openai.ChatCompletionNewParams{
-		Model:    shared.ChatModel(e.modelName),
-		Messages: messages,
-	}
+	preprocessStatus = "success"
+	duration = time.Duration(start.Second())
+	return input, nil
+}
 
-	if req.Generation != nil && req.Generation.MaxTokens != nil {
-		params.MaxCompletionTokens = openai.Int(int64(*req.Generation.MaxTokens))
+func countImages(messages []openai.ChatCompletionMessageParamUnion) int {
+	var images int
+	for _, msg := range messages {
+		if msg.OfUser == nil {
+			continue
+		}
+		for _, part := range msg.OfUser.Content.OfArrayOfContentParts {
+			if part.OfImageURL != nil {
+				images++
+			}
+		}
 	}
+	return images
+}
 
-	if req.Generation != nil && req.Generation.MaxTokens != nil {
-		params.Temperature = openai.Float(*req.Generation.Temperature)
+func applyGeneration(params *openai.ChatCompletionNewParams, generation *models.GenerationParams) {
+	if generation == nil {
+		return
+	}
+	if generation.MaxTokens != nil {
+		params.MaxCompletionTokens = openai.Int(int64(*generation.MaxTokens))
 	}
+	if generation.Temperature != nil {
+		params.Temperature = openai.Float(*generation.Temperature)
+	}
+}
 
-	preprocessStatus = "success"
-	duration = time.Duration(start.Second())
-	return params, nil
+func preprocessImage(req *models.ExplainRequest) *preprocessed {
+	imageData := fmt.Sprintf("data:image/%s;base64,%s", strings.TrimPrefix(req.FileFormat, "."), req.FileAsBase64())
+	return &preprocessed{Images: []string{imageData}, Pages: 1}
 }
 
-func (e *ExplainService) buildImageMessages(req *models.ExplainRequest) []openai.ChatCompletionMessageParamUnion {
-	userPrompt := getUserPrompt(req)
-	imageData := fmt.Sprintf("data:image/%s;base64,%s", strings.TrimPrefix(req.FileFormat, "."), req.FileBase64)
+func preprocessDiagram(req *models.ExplainRequest) (*preprocessed, error) {
+	inputData, err := req.File()
+	if err != nil {
+		return nil, err
+	}
 
-	return []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPromptImage),
-		openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
-			openai.TextContentPart(userPrompt),
-			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
-				URL: imageData,
-			}),
-		}),
+	base64Img, err := convertDiagramToImageTemp(inputData, req.FileFormat)
+	if err != nil {
+		return nil, err
 	}
+
+	imageData := fmt.Sprintf("data:image/%s;base64,%s", JPG, base64Img)
+	return &preprocessed{Images: []string{imageData}, Pages: 1}, nil
 }
 
-func (e *ExplainService) buildDiagramMessages(req *models.ExplainRequest) ([]openai.ChatCompletionMessageParamUnion, error) {
-	userPrompt := getUserPrompt(req)
-	base64Img, err := convertDiagramToImageTemp(req.FileBase64, req.FileFormat)
+// preprocessDrawio feeds the parsed drawio graph as text and falls back
+// to rasterizing the file when it can't be parsed or has no labels. Pages counts
+// the described pages, the raster fallback has the first page only. Files whose
+// compressed pages inflate above the upload size are rejected without the fallback
+func (e *ExplainService) preprocessDrawio(req *models.ExplainRequest) (*preprocessed, error) {
+	inputData, err := req.File()
 	if err != nil {
 		return nil, err
 	}
 
-	imageData := fmt.Sprintf("data:image/%s;base64,%s", JPG, base64Img)
-	return []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPromptImage),
-		openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
-			openai.TextContentPart(userPrompt),
-			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
-				URL: imageData,
-			}),
-		}),
-	}, nil
+	diagram, err := drawio.Parse(inputData, e.maxFileSize)
+	if errors.Is(err, drawio.ErrTooLarge) {
+		return nil, err
+	}
+	if err != nil || diagram.Empty() {
+		if err != nil {
+			e.logger.Printf("drawio parse failed, fallback to image: %v\n", err)
+		}
+		return preprocessDiagram(req)
+	}
+
+	input := preprocessStructure(diagram.Describe())
+	input.Pages = len(diagram.Pages)
+	return input, nil
 }
 
-func (e *ExplainService) buildTxtMessages(req *models.ExplainRequest) ([]openai.ChatCompletionMessageParamUnion, error) {
-	userPrompt := getUserPrompt(req)
-	inputData, err := base64.StdEncoding.DecodeString(req.FileBase64)
+// preprocessBpmn feeds the parsed process model as text and falls back
+// to rendering the file when it can't be parsed or has no names
+func (e *ExplainService) preprocessBpmn(req *models.ExplainRequest) (*preprocessed, error) {
+	inputData, err := req.File()
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64: %w", err)
+		return nil, err
 	}
 
-	userPrompt = fmt.Sprintf("%s\nDiagram text:\n%s", userPrompt, inputData)
-	return []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPromptImage),
-		openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
-			openai.TextContentPart(userPrompt),
-		}),
-	}, nil
+	definitions, err := bpmn.Parse(inputData)
+	if err != nil || definitions.Empty() {
+		if err != nil {
+			e.logger.Printf("bpmn parse failed, fallback to image: %v\n", err)
+		}
+		return preprocessDiagram(req)
+	}
+
+	return preprocessStructure(definitions.Describe()), nil
 }
 
-func (e *ExplainService) buildPdfMessages(req *models.ExplainRequest) ([]openai.ChatCompletionMessageParamUnion, error) {
-	userPrompt := getUserPrompt(req)
-	inputData, err := base64.StdEncoding.DecodeString(req.FileBase64)
+func preprocessStructure(structure string) *preprocessed {
+	return &preprocessed{Text: fmt.Sprintf("Diagram structure:\n%s", structure), Pages: 1}
+}
+
+func preprocessTxt(req *models.ExplainRequest) (*preprocessed, error) {
+	inputData, err := req.File()
+	if err != nil {
+		return nil, err
+	}
+	return &preprocessed{Text: fmt.Sprintf("Diagram text:\n%s", inputData), Pages: 1}, nil
+}
+
+func preprocessPdf(req *models.ExplainRequest) (*preprocessed, error) {
+	inputData, err := req.File()
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64: %w", err)
+		return nil, err
 	}
 
 	doc, err := fitz.NewFromMemory(inputData)
@@ -157,10 +269,7 @@ func (e *ExplainService) buildPdfMessages(req *models.ExplainRequest) ([]openai.
 	}
 	defer doc.Close()
 
-	parts := []openai.ChatCompletionContentPartUnionParam{
-		openai.TextContentPart(userPrompt),
-	}
-
+	input := &preprocessed{Pages: doc.NumPage()}
 	for n := 0; n < doc.NumPage(); n++ {
 		img, err := doc.ImageDPI(n, dpi)
 		if err != nil {
@@ -168,37 +277,22 @@ func (e *ExplainService) buildPdfMessages(req *models.ExplainRequest) ([]openai.
 		}
 
 		var buf bytes.Buffer
-		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
 		if err != nil {
 			return nil, fmt.Errorf("jpeg encode page %d failed: %w", n, err)
 		}
 
 		encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
-		imageData := "data:image/jpeg;base64," + encoded
-
-		parts = append(parts,
-			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
-				URL: imageData,
-			}),
-		)
+		input.Images = append(input.Images, "data:image/jpeg;base64,"+encoded)
 	}
-
-	return []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPromptImage),
-		openai.UserMessage(parts),
-	}, nil
+	return input, nil
 }
 
-func convertDiagramToImageTemp(inputBase64, fileExt string) (string, error) {
+func convertDiagramToImageTemp(inputData []byte, fileExt string) (string, error) {
 	var (
 		cmdArgs []string
 	)
 
-	inputData, err := base64.StdEncoding.DecodeString(inputBase64)
-	if err != nil {
-		return "", fmt.Errorf("failed to decode base64: %w", err)
-	}
-
 	tmpIn, err := os.CreateTemp("", fmt.Sprintf("input-*.%s", fileExt))
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp input file: %w", err)
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+var ErrCacheEntryNotFound = errors.New("cache entry not found")
+
+// adminKeyKinds are kinds of "<namespace>:<kind>:<version>:<file hash>:<hash>" keys managed by the admin API
+var adminKeyKinds = []string{cacheKeyKind, semanticKeyKind, preprocessKeyKind}
+
+// AdminCache is a Cache which can be listed and purged by key prefix.
+// Peek reads entries in one batch without promoting them or making them recently used,
+// missing keys are left out of the result
+type AdminCache interface {
+	Cache
+	Peek(ctx context.Context, keys ...string) (map[string]string, error)
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, keys ...string) (int, error)
+	Stats(ctx context.Context, prefix string) ([]cache.Stats, error)
+}
+
+// CacheAdminService looks up and purges cache entries of every kind under the namespace,
+// jobs, sessions and foreign keys of a shared store are left alone. With the tiered cache
+// deletions reach Redis and the memory tier of this replica only, other replicas keep serving
+// their memory copies until CACHE_MEMORY_TTL
+type CacheAdminService struct {
+	logger    *log.Logger
+	cache     AdminCache
+	explainer *ExplainService
+}
+
+func NewCacheAdminService(logger *log.Logger, cache AdminCache, explainer *ExplainService) *CacheAdminService {
+	return &CacheAdminService{
+		logger:    logger,
+		cache:     cache,
+		explainer: explainer,
+	}
+}
+
+// Lookup returns entries of the current cache version by "<file hash>" or "<file hash>:<request hash>"
+func (s *CacheAdminService) Lookup(ctx context.Context, hash string) ([]models.CacheEntry, error) {
+	keys, err := s.keysByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.peek(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.CacheEntry
+	for _, key := range keys {
+		// missing or expired since listing
+		if value, found := values[key]; found {
+			entries = append(entries, s.entry(key, value))
+		}
+	}
+	if len(entries) == 0 {
+		return nil, ErrCacheEntryNotFound
+	}
+	return entries, nil
+}
+
+// Delete removes entries of the current cache version by "<file hash>" or "<file hash>:<request hash>"
+func (s *CacheAdminService) Delete(ctx context.Context, hash string) (int, error) {
+	keys, err := s.keysByHash(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, ErrCacheEntryNotFound
+	}
+
+	deleted, err := s.cache.Delete(ctx, keys...)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	if deleted == 0 {
+		return 0, ErrCacheEntryNotFound
+	}
+	s.logger.Printf("cache entries of %s deleted: %d\n", hash, deleted)
+	return deleted, nil
+}
+
+// Purge removes entries matching all filters of req. Keys are listed by prefix per kind, a SCAN
+// of the whole keyspace with Redis, so filtering by kind, cache version or prefix scans less.
+// Model and prompt version filters read values of answers only, in MGET batches
+func (s *CacheAdminService) Purge(ctx context.Context, req *models.CachePurgeRequest) (int, error) {
+	kinds := adminKeyKinds
+	if req.Kind != "" {
+		kinds = []string{req.Kind}
+	}
+	// only answers record models and prompt versions
+	if req.Model != "" || req.PromptVersion != "" {
+		kinds = slices.DeleteFunc(slices.Clone(kinds), func(kind string) bool { return kind != cacheKeyKind })
+	}
+	prefix := req.Prefix
+	if prefix == "" && req.CacheVersion != "" {
+		prefix = req.CacheVersion + ":"
+	}
+
+	keys, err := s.keys(ctx, kinds, prefix)
+	if err != nil {
+		return 0, err
+	}
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		kind, version, _, _, ok := s.parseKey(key)
+		return !ok || !slices.Contains(kinds, kind) || req.CacheVersion != "" && version != req.CacheVersion
+	})
+
+	if len(keys) > 0 && (req.Model != "" || req.PromptVersion != "") {
+		values, err := s.peek(ctx, keys)
+		if err != nil {
+			return 0, err
+		}
+		keys = slices.DeleteFunc(keys, func(key string) bool {
+			value, found := values[key]
+			if !found {
+				return true
+			}
+			entry := s.entry(key, value)
+			return req.Model != "" && entry.Model != req.Model || req.PromptVersion != "" && entry.PromptVersion != req.PromptVersion
+		})
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	deleted, err := s.cache.Delete(ctx, keys...)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	s.logger.Printf("cache purged: %d entries deleted\n", deleted)
+	return deleted, nil
+}
+
+// Stats sums entries of every key kind per tier
+func (s *CacheAdminService) Stats(ctx context.Context) (*models.CacheStats, error) {
+	stats := &models.CacheStats{
+		Hits:   s.explainer.cacheHits.Load(),
+		Misses: s.explainer.cacheMisses.Load(),
+	}
+	for _, kind := range adminKeyKinds {
+		tiers, err := s.cache.Stats(ctx, s.kindPrefix(kind))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache stats: %w", err)
+		}
+		for i, tier := range tiers {
+			if i == len(stats.Tiers) {
+				stats.Tiers = append(stats.Tiers, models.CacheTierStats{Tier: tier.Tier})
+			}
+			stats.Tiers[i].Entries += tier.Entries
+			stats.Tiers[i].Bytes += tier.Bytes
+		}
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats, nil
+}
+
+// keysByHash derives the keys of every kind from "<file hash>:<request hash>" without listing,
+// a file hash alone is listed by the "<namespace>:<kind>:<version>:<file hash>:" prefix
+func (s *CacheAdminService) keysByHash(ctx context.Context, hash string) ([]string, error) {
+	fileHash, requestHash, pair := strings.Cut(hash, ":")
+	if fileHash == "" || pair && (requestHash == "" || strings.Contains(requestHash, ":")) {
+		return nil, ErrCacheEntryNotFound
+	}
+
+	if !pair {
+		return s.keys(ctx, adminKeyKinds, fmt.Sprintf("%s:%s:", s.explainer.cacheVersion, fileHash))
+	}
+	keys := make([]string, 0, len(adminKeyKinds))
+	for _, kind := range adminKeyKinds {
+		keys = append(keys, fmt.Sprintf("%s%s:%s:%s", s.kindPrefix(kind), s.explainer.cacheVersion, fileHash, requestHash))
+	}
+	return keys, nil
+}
+
+// keys lists keys of the kinds, prefix is matched after "<namespace>:<kind>:"
+func (s *CacheAdminService) keys(ctx context.Context, kinds []string, prefix string) ([]string, error) {
+	var keys []string
+	for _, kind := range kinds {
+		kindKeys, err := s.cache.Keys(ctx, s.kindPrefix(kind)+prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cache keys: %w", err)
+		}
+		keys = append(keys, kindKeys...)
+	}
+	return keys, nil
+}
+
+func (s *CacheAdminService) kindPrefix(kind string) string {
+	return fmt.Sprintf("%s:%s:", s.explainer.cacheNamespace, kind)
+}
+
+// peek reads the values in one batch, so scans don't change what the cache keeps
+func (s *CacheAdminService) peek(ctx context.Context, keys []string) (map[string]string, error) {
+	values, err := s.cache.Peek(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entries: %w", err)
+	}
+	return values, nil
+}
+
+// entry describes the value of key, undecodable values are still described, so they can be found and deleted
+func (s *CacheAdminService) entry(key, value string) models.CacheEntry {
+	kind, version, fileHash, keyHash, _ := s.parseKey(key)
+	result := models.CacheEntry{
+		Key:          key,
+		Kind:         kind,
+		CacheVersion: version,
+		FileHash:     fileHash,
+		RequestHash:  keyHash,
+		Size:         len(value),
+	}
+
+	switch kind {
+	case cacheKeyKind:
+		answerEntry(&result, value)
+	case semanticKeyKind:
+		var index semanticIndex
+		if err := sonic.UnmarshalString(value, &index); err == nil {
+			for _, prompt := range index.Prompts {
+				result.Prompts = append(result.Prompts, prompt.Prompt)
+			}
+		}
+	case preprocessKeyKind:
+		var input preprocessed
+		if err := sonic.UnmarshalString(value, &input); err == nil {
+			result.Pages = input.Pages
+		}
+	}
+	return result
+}
+
+// answerEntry fills the fields recorded by cached answers
+func answerEntry(result *models.CacheEntry, value string) {
+	entry, err := decodeCacheEntry(value)
+	if err != nil {
+		return
+	}
+	result.Model = entry.Model
+	result.PromptVersion = entry.PromptVersion
+	result.Explanation = entry.Explanation
+	result.Structured = entry.Structured
+	if !entry.CreatedAt.IsZero() {
+		result.CreatedAt = &entry.CreatedAt
+	}
+}
+
+// parseKey splits "<namespace>:<kind>:<version>:<file hash>:<hash>", ok is false for keys of other formats
+func (s *CacheAdminService) parseKey(key string) (kind, version, fileHash, keyHash string, ok bool) {
+	rest, found := strings.CutPrefix(key, s.explainer.cacheNamespace+":")
+	if !found {
+		return "", "", "", "", false
+	}
+	kind, rest, _ = strings.Cut(rest, ":")
+	rest, keyHash = cutLast(rest)
+	version, fileHash = cutLast(rest)
+	ok = slices.Contains(adminKeyKinds, kind) && version != "" && fileHash != "" && keyHash != ""
+	return kind, version, fileHash, keyHash, ok
+}
+
+func cutLast(s string) (before, after string) {
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// listingCache counts key listings, lookups by a full hash must not list
+type listingCache struct {
+	*cache.MemoryCache
+	listings int
+}
+
+func (c *listingCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	c.listings++
+	return c.MemoryCache.Keys(ctx, prefix)
+}
+
+func newTestCacheAdmin(t *testing.T) (*CacheAdminService, *listingCache) {
+	store := &listingCache{MemoryCache: cache.NewMemoryStore(1<<20, time.Hour)}
+	for key, value := range map[string]string{
+		"ns:explain:v1:f1:r1":   `{"explanation":"A calls B","model":"m1","prompt_version":"1"}`,
+		"ns:explain:v1:f1:r2":   `{"explanation":"B calls A","model":"m2","prompt_version":"1"}`,
+		"ns:explain:v1:f2:r3":   `{"explanation":"C","model":"m1","prompt_version":"2"}`,
+		"ns:explain:v0:f1:r1":   `{"explanation":"old","model":"m1"}`,
+		"ns:semantic:v1:f1:s1":  `{"prompts":[{"prompt":"explain"}]}`,
+		"ns:preprocess:v1:f1:p": `{"pages":2}`,
+		"ns:job:a_1":            `{}`,
+	} {
+		if err := store.Set(context.Background(), key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	explainer := &ExplainService{cacheNamespace: "ns", cacheVersion: "v1"}
+	return NewCacheAdminService(log.New(io.Discard, "", 0), store, explainer), store
+}
+
+func entryKeys(entries []models.CacheEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestCacheAdminLookup(t *testing.T) {
+	tests := map[string]struct {
+		hash     string
+		want     []string
+		listings int
+	}{
+		"file hash": {
+			hash:     "f1",
+			want:     []string{"ns:explain:v1:f1:r1", "ns:explain:v1:f1:r2", "ns:preprocess:v1:f1:p", "ns:semantic:v1:f1:s1"},
+			listings: len(adminKeyKinds),
+		},
+		"file and request hash": {hash: "f1:r1", want: []string{"ns:explain:v1:f1:r1"}},
+		"settings hash":         {hash: "f1:p", want: []string{"ns:preprocess:v1:f1:p"}},
+		"unknown request hash":  {hash: "f1:r3"},
+		"request hash alone":    {hash: "r1", listings: len(adminKeyKinds)},
+		"empty file hash":       {hash: ":r1"},
+		"too many parts":        {hash: "f1:r1:x"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, store := newTestCacheAdmin(t)
+
+			entries, err := s.Lookup(context.Background(), tc.hash)
+			if len(tc.want) == 0 {
+				if !errors.Is(err, ErrCacheEntryNotFound) {
+					t.Fatalf("Lookup error = %v, want ErrCacheEntryNotFound", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			if got := entryKeys(entries); !slices.Equal(got, tc.want) {
+				t.Errorf("entries = %v, want %v", got, tc.want)
+			}
+			if store.listings != tc.listings {
+				t.Errorf("listings = %d, want %d", store.listings, tc.listings)
+			}
+		})
+	}
+}
+
+func TestCacheAdminLookupDecodesEntries(t *testing.T) {
+	s, _ := newTestCacheAdmin(t)
+
+	entries, err := s.Lookup(context.Background(), "f1")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case cacheKeyKind:
+			if entry.Model == "" || entry.Explanation == "" || entry.FileHash != "f1" || entry.CacheVersion != "v1" {
+				t.Errorf("answer entry = %+v", entry)
+			}
+		case semanticKeyKind:
+			if !slices.Equal(entry.Prompts, []string{"explain"}) {
+				t.Errorf("semantic prompts = %v", entry.Prompts)
+			}
+		case preprocessKeyKind:
+			if entry.Pages != 2 || entry.RequestHash != "p" {
+				t.Errorf("preprocess entry = %+v", entry)
+			}
+		}
+	}
+}
+
+func TestCacheAdminDelete(t *testing.T) {
+	s, store := newTestCacheAdmin(t)
+
+	deleted, err := s.Delete(context.Background(), "f1:r1")
+	if err != nil || deleted != 1 {
+		t.Fatalf("Delete = %d, err %v, want 1", deleted, err)
+	}
+	if _, found, _ := store.Get(context.Background(), "ns:explain:v0:f1:r1"); !found {
+		t.Error("entry of another cache version deleted")
+	}
+	if _, err := s.Delete(context.Background(), "f1:r1"); !errors.Is(err, ErrCacheEntryNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrCacheEntryNotFound", err)
+	}
+}
+
+func TestCacheAdminPurge(t *testing.T) {
+	tests := map[string]struct {
+		req  models.CachePurgeRequest
+		kept []string
+	}{
+		"model": {
+			req:  models.CachePurgeRequest{Model: "m1"},
+			kept: []string{"ns:explain:v1:f1:r2", "ns:preprocess:v1:f1:p", "ns:semantic:v1:f1:s1"},
+		},
+		"model and prompt version": {
+			req:  models.CachePurgeRequest{Model: "m1", PromptVersion: "1"},
+			kept: []string{"ns:explain:v0:f1:r1", "ns:explain:v1:f1:r2", "ns:explain:v1:f2:r3", "ns:preprocess:v1:f1:p", "ns:semantic:v1:f1:s1"},
+		},
+		"model of another kind": {
+			req:  models.CachePurgeRequest{Kind: semanticKeyKind, Model: "m1"},
+			kept: []string{"ns:explain:v0:f1:r1", "ns:explain:v1:f1:r1", "ns:explain:v1:f1:r2", "ns:explain:v1:f2:r3", "ns:preprocess:v1:f1:p", "ns:semantic:v1:f1:s1"},
+		},
+		"kind": {
+			req:  models.CachePurgeRequest{Kind: preprocessKeyKind},
+			kept: []string{"ns:explain:v0:f1:r1", "ns:explain:v1:f1:r1", "ns:explain:v1:f1:r2", "ns:explain:v1:f2:r3", "ns:semantic:v1:f1:s1"},
+		},
+		"cache version": {
+			req:  models.CachePurgeRequest{CacheVersion: "v0"},
+			kept: []string{"ns:explain:v1:f1:r1", "ns:explain:v1:f1:r2", "ns:explain:v1:f2:r3", "ns:preprocess:v1:f1:p", "ns:semantic:v1:f1:s1"},
+		},
+		"prefix": {
+			req:  models.CachePurgeRequest{Prefix: "v1:f1:"},
+			kept: []string{"ns:explain:v0:f1:r1", "ns:explain:v1:f2:r3"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, store := newTestCacheAdmin(t)
+			before, _ := store.Keys(context.Background(), "ns:")
+
+			deleted, err := s.Purge(context.Background(), &tc.req)
+			if err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+			kept, _ := store.Keys(context.Background(), "ns:")
+			slices.Sort(kept)
+			// jobs are never purged
+			want := append(slices.Clone(tc.kept), "ns:job:a_1")
+			slices.Sort(want)
+			if !slices.Equal(kept, want) {
+				t.Errorf("kept = %v, want %v", kept, want)
+			}
+			if deleted != len(before)-len(kept) {
+				t.Errorf("deleted = %d, want %d", deleted, len(before)-len(kept))
+			}
+		})
+	}
+}
+
+func TestCacheAdminParseKey(t *testing.T) {
+	s, _ := newTestCacheAdmin(t)
+
+	tests := map[string]struct {
+		key                              string
+		kind, version, fileHash, keyHash string
+		ok                               bool
+	}{
+		"answer":            {"ns:explain:v1:f1:r1", "explain", "v1", "f1", "r1", true},
+		"version with ':'":  {"ns:semantic:v1:beta:f1:s1", "semantic", "v1:beta", "f1", "s1", true},
+		"job":               {"ns:job:a_1", "", "", "", "", false},
+		"another namespace": {"other:explain:v1:f1:r1", "", "", "", "", false},
+		"no version":        {"ns:explain:f1:r1", "", "", "", "", false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kind, version, fileHash, keyHash, ok := s.parseKey(tc.key)
+			if ok != tc.ok {
+				t.Fatalf("parseKey(%q) ok = %v, want %v", tc.key, ok, tc.ok)
+			}
+			if ok && (kind != tc.kind || version != tc.version || fileHash != tc.fileHash || keyHash != tc.keyHash) {
+				t.Errorf("parseKey(%q) = %q, %q, %q, %q", tc.key, kind, version, fileHash, keyHash)
+			}
+		})
+	}
+}
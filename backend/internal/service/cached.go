@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// words splits answers cached without stream chunking, whitespace is kept with the next word like in model tokens
+var words = regexp.MustCompile(`\s*\S+\s*$|\s*\S+`)
+
+// cacheEntry is a cached answer. DeltaSizes are byte lengths of the stream deltas,
+// so a cached stream is replayed with the original chunking. Model and PromptVersion
+// let the admin API purge answers of a model or of outdated prompts
+type cacheEntry struct {
+	Explanation   string                        `json:"explanation"`
+	Structured    *models.StructuredExplanation `json:"structured,omitempty"`
+	DeltaSizes    []int                         `json:"delta_sizes,omitempty"`
+	Model         string                        `json:"model,omitempty"`
+	PromptVersion string                        `json:"prompt_version,omitempty"`
+	CreatedAt     time.Time                     `json:"created_at"`
+}
+
+func cacheValue(response *models.ExplainResponse, deltaSizes []int) string {
+	data, err := sonic.MarshalString(cacheEntry{
+		Explanation:   response.Explanation,
+		Structured:    response.Structured,
+		DeltaSizes:    deltaSizes,
+		Model:         response.Model,
+		PromptVersion: promptVersion,
+		CreatedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return ""
+	}
+	return data
+}
+
+func decodeCacheEntry(cached string) (*cacheEntry, error) {
+	var entry cacheEntry
+	if err := sonic.UnmarshalString(cached, &entry); err != nil {
+		return nil, fmt.Errorf("invalid cache entry: %w", err)
+	}
+	return &entry, nil
+}
+
+func cachedResponse(req *models.ExplainRequest, cached string) (*models.ExplainResponse, error) {
+	entry, err := decodeCacheEntry(cached)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Structured() {
+		if entry.Structured == nil {
+			return nil, fmt.Errorf("cache entry has no structured answer")
+		}
+		if err := entry.Structured.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &models.ExplainResponse{
+		Explanation: entry.Explanation,
+		Structured:  entry.Structured,
+		Cached:      true,
+	}, nil
+}
+
+// deltas returns the original stream deltas or the answer split by words
+func (c *cacheEntry) deltas() []string {
+	total := 0
+	for _, size := range c.DeltaSizes {
+		total += size
+	}
+	if total != len(c.Explanation) || total == 0 {
+		return words.FindAllString(c.Explanation, -1)
+	}
+
+	deltas := make([]string, 0, len(c.DeltaSizes))
+	rest := c.Explanation
+	for _, size := range c.DeltaSizes {
+		deltas = append(deltas, rest[:size])
+		rest = rest[size:]
+	}
+	return deltas
+}
+
+// replay sends the cached answer as message deltas, waiting replayDelay between them
+func (e *ExplainService) replay(ctx context.Context, ch chan<- models.StreamChunk, entry *cacheEntry) {
+	for i, delta := range entry.deltas() {
+		if i > 0 && e.replayDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(e.replayDelay):
+			}
+		}
+		if !sendOrStop(ctx, ch, models.StreamChunk{Delta: delta}) {
+			return
+		}
+	}
+	sendOrStop(ctx, ch, models.StreamChunk{Done: true})
+}
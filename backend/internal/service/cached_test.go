@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+func TestCacheEntryDeltas(t *testing.T) {
+	tests := map[string]struct {
+		entry cacheEntry
+		want  []string
+	}{
+		"original chunking": {
+			entry: cacheEntry{Explanation: "A calls B", DeltaSizes: []int{3, 4, 2}},
+			want:  []string{"A c", "alls", " B"},
+		},
+		"without chunking": {
+			entry: cacheEntry{Explanation: "A calls\nB "},
+			want:  []string{"A", " calls", "\nB "},
+		},
+		// sizes of another answer, e.g. edited by hand
+		"sizes mismatch": {
+			entry: cacheEntry{Explanation: "A calls B", DeltaSizes: []int{3, 4}},
+			want:  []string{"A", " calls", " B"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.entry.deltas()
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("deltas = %q, want %q", got, tc.want)
+			}
+			if strings.Join(got, "") != tc.entry.Explanation {
+				t.Errorf("deltas %q don't add up to the answer", got)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	e := &ExplainService{replayDelay: delay}
+	entry := &cacheEntry{Explanation: "A calls B", DeltaSizes: []int{1, 6, 2}}
+
+	ch := make(chan models.StreamChunk, 10)
+	start := time.Now()
+	e.replay(context.Background(), ch, entry)
+	close(ch)
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("replay took %s, want at least %s between 3 deltas", elapsed, 2*delay)
+	}
+
+	var chunks []models.StreamChunk
+	for chunk := range ch {
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) != 4 || !chunks[3].Done || chunks[0].Delta != "A" || chunks[2].Delta != " B" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+}
+
+func TestReplayStopsOnCancel(t *testing.T) {
+	e := &ExplainService{replayDelay: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := make(chan models.StreamChunk, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.replay(ctx, ch, &cacheEntry{Explanation: "A calls B"})
+	}()
+	if chunk := <-ch; chunk.Delta != "A" {
+		t.Fatalf("first chunk = %+v", chunk)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("replay kept waiting after cancel")
+	}
+	if len(ch) != 0 {
+		t.Errorf("chunks sent after cancel: %d", len(ch))
+	}
+}
+
+func TestCachedResponse(t *testing.T) {
+	text := cacheValue(&models.ExplainResponse{Explanation: "A calls B"}, nil)
+	structured := cacheValue(&models.ExplainResponse{
+		Explanation: "A calls B",
+		Structured:  &models.StructuredExplanation{DiagramType: "flowchart", Summary: "A calls B"},
+	}, nil)
+
+	resp, err := cachedResponse(&models.ExplainRequest{}, text)
+	if err != nil || !resp.Cached || resp.Explanation != "A calls B" {
+		t.Fatalf("text answer = %+v, err %v", resp, err)
+	}
+	if _, err := cachedResponse(&models.ExplainRequest{Output: models.OutputStructured}, text); err == nil {
+		t.Error("text answer served to a structured request")
+	}
+	if resp, err := cachedResponse(&models.ExplainRequest{Output: models.OutputStructured}, structured); err != nil || resp.Structured == nil {
+		t.Errorf("structured answer = %+v, err %v", resp, err)
+	}
+	if _, err := cachedResponse(&models.ExplainRequest{}, "not json"); err == nil {
+		t.Error("invalid entry decoded")
+	}
+}
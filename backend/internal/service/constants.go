@@ -16,5 +16,34 @@ const (
 You are an assistant. Explain the uploaded diagram briefly and clearly.
 User can give extra information or ask certain questions about the diagram.`
 
+	systemPromptStructured = `
+Answer only with a JSON object: diagram type, title, components with descriptions,
+relationships between components with direction and label, detected issues and a short summary.`
+
+	structuredRetryPrompt = "The previous answer is invalid: %s. Reply again with only a valid JSON object matching the schema."
+
+	systemPromptConvert = `
+You are an assistant. Convert the uploaded diagram to %s source code.
+Keep all components, labels and connections of the diagram. %s
+Answer only with the diagram code, without explanations.`
+
+	convertRetryPrompt = "The %s code is invalid: %s. Fix it and reply again with only the diagram code."
+
+	systemPromptSession = `
+You are an assistant. Answer questions about the uploaded diagram briefly and clearly.
+Take previous answers of the conversation into account.`
+
 	userPromptTemplate = "Filename: %s"
 )
+
+const (
+	cacheKeyKind      = "explain"
+	semanticKeyKind   = "semantic"
+	preprocessKeyKind = "preprocess"
+	jobKeyKind        = "job"
+	sessionKeyKind    = "session"
+
+	// promptVersion must be bumped when the diagram preprocessing output changes, it invalidates
+	// cached preprocessing and answers. Prompt texts are hashed into the cache key on their own
+	promptVersion = "1"
+)
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/mermaid"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/plantuml"
+	"github.com/openai/openai-go/v3"
+)
+
+var ErrInvalidConversion = errors.New("model returned invalid diagram code")
+
+const convertAttempts = 3
+
+type convertTarget struct {
+	name     string
+	hint     string
+	validate func(src string) error
+}
+
+var convertTargets = map[string]convertTarget{
+	models.TargetMermaid: {
+		name:     "Mermaid",
+		hint:     "Use flowchart, sequenceDiagram or classDiagram syntax, quote labels with special characters.",
+		validate: mermaid.Validate,
+	},
+	models.TargetPlantUML: {
+		name:     "PlantUML",
+		hint:     "Use sequence, component or class diagram syntax between @startuml and @enduml.",
+		validate: plantuml.Validate,
+	},
+}
+
+// Convert turns the diagram into target language code. Code failing syntax validation
+// is sent back to the model together with the error
+func (e *ExplainService) Convert(ctx context.Context, req *models.ConvertRequest) (*models.ConvertResponse, error) {
+	target, ok := convertTargets[req.Target]
+	if !ok {
+		return nil, fmt.Errorf("unsupported target %q", req.Target)
+	}
+
+	formatInfo := e.resolveFormat(&req.ExplainRequest)
+	if err := checkFormat(req.FileFormat); err != nil {
+		return nil, err
+	}
+	req.Output = models.OutputText
+
+	// text diagrams are often already written in the target language
+	if req.FileFormat == TXT {
+		if data, err := req.File(); err == nil {
+			if source := strings.TrimSpace(string(data)); target.validate(source) == nil {
+				e.logger.Printf("file %s is already valid %s code\n", req.FileName, req.Target)
+				return &models.ConvertResponse{
+					Target:     req.Target,
+					Source:     source,
+					FormatInfo: formatInfo,
+				}, nil
+			}
+		}
+	}
+
+	params, _, err := e.buildOpenAIReq(ctx, &req.ExplainRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	// builders always start with the explanation system prompt
+	params.Messages[0] = openai.SystemMessage(fmt.Sprintf(systemPromptConvert, target.name, target.hint))
+
+	var lastErr error
+	for attempt := 1; attempt <= convertAttempts; attempt++ {
+		resp, _, err := e.complete(ctx, params, req.FileFormat)
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI client error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("OpenAI client error: empty choices")
+		}
+
+		content := resp.Choices[0].Message.Content
+		source := extractCode(content)
+		if lastErr = target.validate(source); lastErr == nil {
+			return &models.ConvertResponse{
+				Target:     req.Target,
+				Source:     source,
+				Attempts:   attempt,
+				Model:      string(params.Model),
+				FormatInfo: formatInfo,
+			}, nil
+		}
+
+		e.logger.Printf("invalid %s code, attempt %d/%d: %v\n", req.Target, attempt, convertAttempts, lastErr)
+		params.Messages = append(params.Messages,
+			openai.AssistantMessage(content),
+			openai.UserMessage(fmt.Sprintf(convertRetryPrompt, target.name, lastErr)),
+		)
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrInvalidConversion, lastErr)
+}
+
+// extractCode returns the first fenced code block of the answer or the whole answer
+func extractCode(content string) string {
+	content = strings.TrimSpace(content)
+	_, block, found := strings.Cut(content, "```")
+	if !found {
+		return content
+	}
+
+	// drop the language tag line
+	if newline := strings.IndexByte(block, '\n'); newline >= 0 {
+		block = block[newline+1:]
+	}
+	block, _, _ = strings.Cut(block, "```")
+	return strings.TrimSpace(block)
+}
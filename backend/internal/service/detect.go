@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+const sniffLen = 4096
+
+// ErrUnsupportedFormat is returned for files of formats no preprocessor handles
+var ErrUnsupportedFormat = errors.New("unsupported file format")
+
+// supportedFormats are the formats runPreprocess handles
+var supportedFormats = map[string]bool{
+	PNG:    true,
+	JPEG:   true,
+	JPG:    true,
+	DRAWIO: true,
+	BPMN:   true,
+	SVG:    true,
+	TXT:    true,
+	PDF:    true,
+}
+
+var (
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	jpegMagic = []byte{0xFF, 0xD8, 0xFF}
+	pdfMagic  = []byte("%PDF-")
+	utf8BOM   = []byte{0xEF, 0xBB, 0xBF}
+
+	// diagram-as-code markers, checked at line starts of the first lines
+	textMarkers = []string{
+		"@startuml", "@startmindmap", "@startgantt", "@startwbs", "@startjson", "@startyaml",
+		"participant ", "actor ", "sequenceDiagram", "classDiagram", "flowchart ",
+		"graph ", "erDiagram", "stateDiagram", "digraph ",
+	}
+)
+
+// detectFormat sniffs file content: magic bytes for binaries, root element for XML
+// and diagram-as-code markers for text. Returns empty string if nothing matched
+func detectFormat(data []byte) string {
+	return detectReader(bytes.NewReader(data))
+}
+
+// detectReader is detectFormat reading only as much of r as it needs
+func detectReader(r io.Reader) string {
+	data := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+	data = data[:n]
+
+	switch {
+	case bytes.HasPrefix(data, pngMagic):
+		return PNG
+	case bytes.HasPrefix(data, jpegMagic):
+		return JPG
+	case bytes.HasPrefix(data, pdfMagic):
+		return PDF
+	}
+
+	head := bytes.TrimPrefix(data, utf8BOM)
+	trimmed := bytes.TrimSpace(head)
+
+	if hasTextMarker(trimmed) {
+		return TXT
+	}
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return detectXMLFormat(io.MultiReader(bytes.NewReader(head), r))
+	}
+	if isText(head) {
+		return TXT
+	}
+	return ""
+}
+
+func detectXMLFormat(r io.Reader) string {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "mxfile", "mxGraphModel":
+			return DRAWIO
+		case "definitions":
+			if strings.Contains(strings.ToLower(start.Name.Space), "bpmn") {
+				return BPMN
+			}
+			for _, attr := range start.Attr {
+				if strings.Contains(strings.ToLower(attr.Value), "bpmn") {
+					return BPMN
+				}
+			}
+			return ""
+		case "svg":
+			return SVG
+		default:
+			return ""
+		}
+	}
+}
+
+func hasTextMarker(head []byte) bool {
+	lines := strings.SplitN(string(head), "\n", 10)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		for _, marker := range textMarkers {
+			if strings.HasPrefix(line, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isText(head []byte) bool {
+	if len(head) == 0 || bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	// the sniffed prefix may cut a multibyte rune in half
+	for i := 0; i < utf8.UTFMax && len(head) > 0; i++ {
+		if utf8.Valid(head) {
+			return true
+		}
+		head = head[:len(head)-1]
+	}
+	return false
+}
+
+// normalizeFormat accepts "PNG", ".png" or "image/png" like values
+func normalizeFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	format = strings.TrimPrefix(format, ".")
+	if _, sub, ok := strings.Cut(format, "/"); ok {
+		format = sub
+	}
+	switch format {
+	case "svg+xml":
+		return SVG
+	case "mxfile":
+		return DRAWIO
+	case "text", "plain", "puml", "plantuml", "mmd":
+		return TXT
+	}
+	return format
+}
+
+// checkFormat rejects formats left unsupported after resolveFormat
+func checkFormat(format string) error {
+	if !supportedFormats[format] {
+		return fmt.Errorf("%w %q", ErrUnsupportedFormat, format)
+	}
+	return nil
+}
+
+func sameFormat(a, b string) bool {
+	isJPEG := func(f string) bool { return f == JPG || f == JPEG }
+	return a == b || (isJPEG(a) && isJPEG(b))
+}
+
+// resolveFormat fills req.FileFormat from content sniffing. The detected format wins
+// over a declared one, the mismatch is returned as a warning
+func (e *ExplainService) resolveFormat(req *models.ExplainRequest) models.FormatInfo {
+	format, detected := resolvedFormat(req)
+	info := models.FormatInfo{DeclaredFormat: req.FileFormat, DetectedFormat: detected}
+
+	if declared := normalizeFormat(req.FileFormat); declared != "" && format != declared {
+		info.FormatWarning = fmt.Sprintf("declared format %q does not match detected %q, using detected", req.FileFormat, detected)
+		e.logger.Printf("file %s: %s\n", req.FileName, info.FormatWarning)
+	}
+	req.FileFormat = format
+	return info
+}
+
+// resolvedFormat returns the format resolveFormat picks for req without changing it,
+// detected is empty when the content is not recognized
+func resolvedFormat(req *models.ExplainRequest) (format, detected string) {
+	declared := normalizeFormat(req.FileFormat)
+	detected = detectReader(req.FileReader())
+
+	switch {
+	case detected == "":
+		return declared, detected
+	case declared != "" && sameFormat(declared, detected):
+		// keeps the declared jpeg spelling, both are handled the same way
+		return declared, detected
+	default:
+		return detected, detected
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+const benchmarkData = "../../../benchmark/data"
+
+func TestDetectFormatBenchmark(t *testing.T) {
+	formats := map[string]string{
+		"png":    PNG,
+		"jpg":    JPG,
+		"drawio": DRAWIO,
+		"bpmn":   BPMN,
+		"txt":    TXT,
+	}
+	for dir, want := range formats {
+		files, err := filepath.Glob(filepath.Join(benchmarkData, dir, "*"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no benchmark files in %s", dir)
+		}
+		for _, file := range files {
+			t.Run(filepath.Base(file), func(t *testing.T) {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := detectFormat(data); got != want {
+					t.Errorf("detectFormat = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	// a two byte rune cut by the sniffed prefix
+	cutRune := strings.Repeat("a", sniffLen-1) + "я"
+
+	tests := map[string]struct {
+		data string
+		want string
+	}{
+		"pdf":                 {"%PDF-1.7\n%\xe2\xe3", PDF},
+		"svg":                 {`<?xml version="1.0"?><!-- logo --><svg xmlns="http://www.w3.org/2000/svg"/>`, SVG},
+		"svg with bom":        {"\xef\xbb\xbf<svg/>", SVG},
+		"bare mxGraphModel":   {`<mxGraphModel><root/></mxGraphModel>`, DRAWIO},
+		"bpmn namespace":      {`<bpmn2:definitions xmlns:bpmn2="http://www.omg.org/spec/BPMN/20100524/MODEL"/>`, BPMN},
+		"bpmn attribute":      {`<definitions xmlns="http://www.omg.org/spec/BPMN/20100524/MODEL"/>`, BPMN},
+		"foreign definitions": {`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`, ""},
+		"foreign xml":         {`<html><body/></html>`, ""},
+		"broken xml":          {`<<<`, ""},
+		"plantuml":            {"\n\n@startuml\nA -> B\n@enduml", TXT},
+		"mermaid":             {"graph TD\nA --> B", TXT},
+		"marker after xml":    {"<!-- -->\nsequenceDiagram\nA->>B: hi", TXT},
+		"plain text":          {"Клиент отправляет запрос", TXT},
+		"cut rune":            {cutRune, TXT},
+		"binary":              {"\x00\x01\x02\x03", ""},
+		"empty":               {"", ""},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := detectFormat([]byte(tc.data)); got != tc.want {
+				t.Errorf("detectFormat = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	tests := map[string]string{
+		"PNG":             PNG,
+		".png":            PNG,
+		" .JPG ":          JPG,
+		"image/jpeg":      JPEG,
+		"image/svg+xml":   SVG,
+		"application/pdf": PDF,
+		"mxfile":          DRAWIO,
+		"text/plain":      TXT,
+		"puml":            TXT,
+		"mmd":             TXT,
+		"":                "",
+		"docx":            "docx",
+	}
+	for format, want := range tests {
+		if got := normalizeFormat(format); got != want {
+			t.Errorf("normalizeFormat(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestResolveFormat(t *testing.T) {
+	png, err := os.ReadFile(filepath.Join(benchmarkData, "png", "C1.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	drawio, err := os.ReadFile(filepath.Join(benchmarkData, "drawio", "C4.drawio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &ExplainService{logger: log.New(io.Discard, "", 0)}
+
+	tests := map[string]struct {
+		declared string
+		data     []byte
+		want     string
+		warning  bool
+	}{
+		"matching":           {"image/png", png, PNG, false},
+		"undeclared":         {"", drawio, DRAWIO, false},
+		"mismatch":           {"png", drawio, DRAWIO, true},
+		"mismatch binary":    {"drawio", png, PNG, true},
+		"jpeg spelling kept": {"JPEG", []byte("\xff\xd8\xff\xe0"), JPEG, false},
+		"undetected":         {".PDF", []byte{0, 1, 2}, PDF, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := &models.ExplainRequest{FileName: "file", FileFormat: tc.declared, FileData: tc.data}
+			info := e.resolveFormat(req)
+			if req.FileFormat != tc.want {
+				t.Errorf("FileFormat = %q, want %q", req.FileFormat, tc.want)
+			}
+			if info.DeclaredFormat != tc.declared {
+				t.Errorf("DeclaredFormat = %q, want %q", info.DeclaredFormat, tc.declared)
+			}
+			if (info.FormatWarning != "") != tc.warning {
+				t.Errorf("FormatWarning = %q, want warning %v", info.FormatWarning, tc.warning)
+			}
+		})
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	for format := range supportedFormats {
+		if err := checkFormat(format); err != nil {
+			t.Errorf("checkFormat(%q) = %v", format, err)
+		}
+	}
+	if err := checkFormat("docx"); err == nil {
+		t.Error("checkFormat(docx) = nil, want ErrUnsupportedFormat")
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/llm"
 	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
 	"github.com/openai/openai-go/v3"
 )
@@ -18,54 +22,167 @@ type Cache interface {
 	Set(ctx context.Context, key string, value string) error
 }
 
+// modelQueue limits concurrent model calls
+type modelQueue interface {
+	Acquire(ctx context.Context, onPosition func(position int)) (release func(), err error)
+}
+
 type ExplainService struct {
-	logger       *log.Logger
-	openaiClient openai.Client
-	modelName    string
-	cache        Cache
+	logger      *log.Logger
+	backends    *llm.Pool
+	queue       modelQueue
+	routes      llm.Routes
+	modelName   string
+	maxFileSize int64
+
+	// renderings of files running at once are limited by the capacity
+	renderSlots chan struct{}
+
+	cache          Cache
+	cacheNamespace string
+	cachePrefix    string
+	cacheVersion   string
+	replayDelay    time.Duration
+	semantic       *semanticCache
+
+	// preprocessing of rendered formats is cached when positive
+	preprocessMaxBytes int
+
+	// cache lookups since start, reported by the admin API
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+
+	// identical requests in flight share one generation
+	sends   *flightGroup[sendResult]
+	streams *flightGroup[models.StreamChunk]
 }
 
-func NewExplainService(logger *log.Logger, openaiClient openai.Client, cfg config.OpenAIConfig) *ExplainService {
+type sendResult struct {
+	response *models.ExplainResponse
+	err      error
+}
+
+// NewExplainService limits files unpacked during preprocessing by the upload size
+// and renderings running at once by QUEUE_PREPROCESS_CONCURRENCY
+func NewExplainService(logger *log.Logger, backends *llm.Pool, queue modelQueue, cfg config.OpenAIConfig, upload config.UploadConfig, queueCfg config.QueueConfig) *ExplainService {
 	return &ExplainService{
-		logger:       logger,
-		openaiClient: openaiClient,
-		modelName:    cfg.Model,
+		logger:      logger,
+		backends:    backends,
+		queue:       queue,
+		routes:      llm.ParseRoutes(cfg.Routes),
+		modelName:   cfg.Model,
+		maxFileSize: upload.MaxSize,
+		renderSlots: make(chan struct{}, max(queueCfg.PreprocessConcurrency, 1)),
+		sends:       newFlightGroup[sendResult]("send"),
+		streams:     newFlightGroup[models.StreamChunk]("stream"),
 	}
 }
 
-func (e *ExplainService) SetCacheClient(cache Cache) {
+// SetCacheClient enables caching, keys are put under namespace and changing version invalidates
+// all previously cached keys. Cached answers are streamed with replayDelay between deltas
+func (e *ExplainService) SetCacheClient(cache Cache, namespace, version string, replayDelay time.Duration) {
 	e.cache = cache
+	e.cacheNamespace = namespace
+	e.cachePrefix = fmt.Sprintf("%s:%s:", namespace, cacheKeyKind)
+	e.cacheVersion = version
+	e.replayDelay = replayDelay
+}
+
+// lookup reads the cache and counts hits and misses
+func (e *ExplainService) lookup(ctx context.Context, key string) (string, bool) {
+	cached, found, err := e.cache.Get(ctx, key)
+	if err != nil {
+		e.logger.Printf("cache get error: %v\n", err)
+	}
+	if found {
+		e.cacheHits.Add(1)
+	} else {
+		e.cacheMisses.Add(1)
+	}
+	return cached, found
 }
 
 func (e *ExplainService) Send(ctx context.Context, req *models.ExplainRequest) (*models.ExplainResponse, error) {
-	if e.cache != nil {
-		cached, found, err := e.cache.Get(ctx, getCacheKey(req))
-		if err != nil {
-			e.logger.Printf("cache get error: %v\n", err)
-		}
-		if found {
-			e.logger.Println("served from cache")
-			return &models.ExplainResponse{Explanation: cached}, nil
+	formatInfo := e.resolveFormat(req)
+	if err := checkFormat(req.FileFormat); err != nil {
+		return nil, err
+	}
+	return e.sendResolved(ctx, req, formatInfo)
+}
+
+// sendResolved is Send for requests whose format is already resolved
+func (e *ExplainService) sendResolved(ctx context.Context, req *models.ExplainRequest, formatInfo models.FormatInfo) (*models.ExplainResponse, error) {
+	key := e.getCacheKey(req)
+
+	cached, similarity, query, found := e.cached(ctx, req, key)
+	if found {
+		e.logger.Println("served from cache")
+		response, err := cachedResponse(req, cached)
+		if err == nil {
+			response.FormatInfo = formatInfo
+			response.Similarity = similarity
+			return response, nil
 		}
+		e.logger.Printf("invalid cache entry: %v\n", err)
 	}
 
-	params, err := e.buildOpenAIReq(req)
+	f := e.sends.join(ctx, key, func(ctx context.Context, emit func(sendResult)) {
+		response, err := e.send(ctx, req, key, query)
+		emit(sendResult{response: response, err: err})
+	})
+	defer e.sends.leave(f)
+
+	results, _, err := f.read(ctx, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build request: %w", err)
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("request was cancelled")
+	}
+	if results[0].err != nil {
+		return nil, results[0].err
 	}
 
-	resp, err := e.openaiClient.Chat.Completions.New(ctx, *params)
+	// the response is shared, the format info is of this request
+	response := *results[0].response
+	response.FormatInfo = formatInfo
+	return &response, nil
+}
+
+// send generates the answer and caches it, query indexes it in the semantic cache
+func (e *ExplainService) send(ctx context.Context, req *models.ExplainRequest, cacheKey string, query *semanticQuery) (*models.ExplainResponse, error) {
+	params, _, err := e.buildOpenAIReq(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI client error: %w", err)
+		return nil, fmt.Errorf("failed to build request: %w", err)
 	}
 
-	response := &models.ExplainResponse{
-		Explanation: resp.Choices[0].Message.Content,
+	var response *models.ExplainResponse
+	if req.Structured() {
+		response, err = e.sendStructured(ctx, params, req.FileFormat)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		resp, stats, err := e.complete(ctx, params, req.FileFormat)
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI client error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("OpenAI client error: empty choices")
+		}
+		response = &models.ExplainResponse{
+			Explanation: resp.Choices[0].Message.Content,
+			Model:       stats.model(),
+			Latency:     stats.finish(),
+			Usage:       stats.usage,
+		}
 	}
 
 	if e.cache != nil {
-		if err := e.cache.Set(ctx, getCacheKey(req), response.Explanation); err != nil {
+		if err := e.cache.Set(ctx, cacheKey, cacheValue(response, nil)); err != nil {
 			e.logger.Printf("failed to set cache: %v\n", err)
+		} else {
+			e.semanticAdd(ctx, query, cacheKey)
 		}
 	}
 	return response, nil
@@ -75,102 +192,267 @@ func (e *ExplainService) SendStream(
 	ctx context.Context,
 	req *models.ExplainRequest,
 ) (<-chan models.StreamChunk, error) {
-	ch := make(chan models.StreamChunk, 1)
-
-	if e.cache != nil {
-		cached, found, err := e.cache.Get(ctx, getCacheKey(req))
-		if err != nil {
-			e.logger.Printf("cache get error: %v\n", err)
-		}
-		if found {
-			ch <- models.StreamChunk{Delta: cached, Done: true}
-			close(ch)
-			return ch, nil
-		}
+	formatInfo := e.resolveFormat(req)
+	if err := checkFormat(req.FileFormat); err != nil {
+		return nil, err
 	}
-
-	params, err := e.buildOpenAIReq(req)
-	if err != nil {
-		return nil, fmt.Errorf("build request error: %w", err)
+	if req.Structured() {
+		return e.sendStructuredStream(ctx, req, formatInfo), nil
 	}
 
+	ch := make(chan models.StreamChunk, 2)
+	ch <- stageChunk(models.StageQueued)
+
 	go func() {
 		defer close(ch)
 
-		sendOrStop := func(msg models.StreamChunk) bool {
-			select {
-			case ch <- msg:
-				return true
-			case <-ctx.Done():
-				return false
-			}
-		}
+		key := e.getCacheKey(req)
 
-		sendNonBlocking := func(msg models.StreamChunk) {
-			select {
-			case ch <- msg:
-			default:
+		var entry *cacheEntry
+		cached, similarity, query, found := e.cached(ctx, req, key)
+		if found {
+			var err error
+			if entry, err = decodeCacheEntry(cached); err != nil {
+				e.logger.Printf("%v\n", err)
 			}
 		}
 
-		stream := e.openaiClient.Chat.Completions.NewStreaming(ctx, *params)
-		defer stream.Close()
-
-		var builder strings.Builder
+		meta := &models.StreamMeta{FormatInfo: formatInfo, Cached: entry != nil}
+		if entry != nil {
+			meta.Similarity = similarity
+		}
+		if !sendOrStop(ctx, ch, models.StreamChunk{Meta: meta}) {
+			return
+		}
+		if entry != nil {
+			e.logger.Println("served from cache")
+			e.replay(ctx, ch, entry)
+			return
+		}
 
-		for stream.Next() {
-			if ctx.Err() != nil {
-				sendNonBlocking(models.StreamChunk{Err: ctx.Err()})
-				return
+		// followers get the chunks the leader has already received, then the same deltas live
+		f := e.streams.join(ctx, key, func(ctx context.Context, emit func(models.StreamChunk)) {
+			chunks := make(chan models.StreamChunk)
+			go func() {
+				defer close(chunks)
+				e.generateStream(ctx, req, key, query, chunks)
+			}()
+			for chunk := range chunks {
+				emit(chunk)
 			}
+		})
+		defer e.streams.leave(f)
 
-			chunk := stream.Current()
-			if len(chunk.Choices) == 0 {
-				continue
+		for from := 0; ; {
+			chunks, done, err := f.read(ctx, from)
+			if err != nil {
+				return
 			}
-
-			delta := chunk.Choices[0].Delta.Content
-			if delta == "" {
-				continue
+			for _, chunk := range chunks {
+				if !sendOrStop(ctx, ch, chunk) {
+					return
+				}
 			}
-
-			builder.WriteString(delta)
-			if !sendOrStop(models.StreamChunk{Delta: delta}) {
+			from += len(chunks)
+			if done {
 				return
 			}
 		}
+	}()
 
-		if err := stream.Err(); err != nil {
-			sendNonBlocking(models.StreamChunk{Err: err})
-			return
-		}
+	return ch, nil
+}
 
+// generateStream preprocesses the file, streams the answer to ch and caches it, query indexes it in the semantic cache
+func (e *ExplainService) generateStream(ctx context.Context, req *models.ExplainRequest, cacheKey string, query *semanticQuery, ch chan<- models.StreamChunk) {
+	if !sendOrStop(ctx, ch, stageChunk(models.StagePreprocessing)) {
+		return
+	}
+	params, info, err := e.buildOpenAIReq(ctx, req)
+	if err != nil {
+		sendOrStop(ctx, ch, models.StreamChunk{Err: fmt.Errorf("build request error: %w", err)})
+		return
+	}
+	if !sendOrStop(ctx, ch, models.StreamChunk{Stage: &models.StreamStage{Name: models.StagePreprocessed, PreprocessInfo: info}}) {
+		return
+	}
+
+	e.streamCompletion(ctx, params, req.FileFormat, ch, func(answer, model string, deltaSizes []int) {
 		if e.cache != nil {
-			if err := e.cache.Set(ctx, getCacheKey(req), builder.String()); err != nil {
+			value := cacheValue(&models.ExplainResponse{Explanation: answer, Model: model}, deltaSizes)
+			if err := e.cache.Set(ctx, cacheKey, value); err != nil {
 				e.logger.Printf("failed to set cache: %v", err)
+			} else {
+				e.semanticAdd(ctx, query, cacheKey)
 			}
 		}
+	})
+}
 
-		sendNonBlocking(models.StreamChunk{Done: true})
-	}()
+// streamCompletion sends the generating stage, completion deltas, usage and latency to ch.
+// onDone gets the full answer, the model and byte sizes of the deltas before the final chunk and isn't called on errors
+func (e *ExplainService) streamCompletion(
+	ctx context.Context,
+	params *openai.ChatCompletionNewParams,
+	format string,
+	ch chan<- models.StreamChunk,
+	onDone func(answer, model string, deltaSizes []int),
+) {
+	sendNonBlocking := func(msg models.StreamChunk) {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
 
-	return ch, nil
+	// the position is reported only while the call waits for a free slot
+	release, err := e.queue.Acquire(ctx, func(position int) {
+		sendOrStop(ctx, ch, models.StreamChunk{Stage: &models.StreamStage{Name: models.StageQueued, Position: position}})
+	})
+	if err != nil {
+		sendOrStop(ctx, ch, models.StreamChunk{Err: err})
+		return
+	}
+	defer release()
+
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+	stats := newCompletionStats(format, params)
+	stream, err := e.backends.Stream(ctx, params, e.routedModels(params, format)...)
+	if err != nil {
+		sendOrStop(ctx, ch, models.StreamChunk{Err: err})
+		return
+	}
+	defer stream.Close()
+
+	generating := &models.StreamStage{Name: models.StageGenerating, Model: stream.Model()}
+	if !sendOrStop(ctx, ch, models.StreamChunk{Stage: generating}) {
+		return
+	}
+
+	var (
+		builder    strings.Builder
+		deltaSizes []int
+	)
+
+	for stream.Next() {
+		if ctx.Err() != nil {
+			sendNonBlocking(models.StreamChunk{Err: ctx.Err()})
+			return
+		}
+
+		chunk := stream.Current()
+		// usage comes in the last chunk without choices
+		stats.addUsage(chunk.Usage)
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+
+		stats.token()
+		builder.WriteString(delta)
+		deltaSizes = append(deltaSizes, len(delta))
+		if !sendOrStop(ctx, ch, models.StreamChunk{Delta: delta}) {
+			return
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		sendOrStop(ctx, ch, models.StreamChunk{Err: err})
+		return
+	}
+
+	if onDone != nil {
+		onDone(builder.String(), stream.Model(), deltaSizes)
+	}
+
+	usage := &models.StreamStage{Name: models.StageUsage, Model: stream.Model(), Usage: stats.usage, Latency: stats.finish()}
+	if !sendOrStop(ctx, ch, models.StreamChunk{Stage: usage}) {
+		return
+	}
+	sendOrStop(ctx, ch, models.StreamChunk{Done: true})
 }
 
-func getCacheKey(req *models.ExplainRequest) string {
-	data := []string{
-		req.FileName,
-		req.Prompt,
+// complete runs a chat completion on the least loaded backend of the routed models
+// once the queue admits it
+// complete runs a non-streamed call, stats measure the call without the queue wait
+func (e *ExplainService) complete(ctx context.Context, params *openai.ChatCompletionNewParams, format string) (*openai.ChatCompletion, *completionStats, error) {
+	release, err := e.queue.Acquire(ctx, nil)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer release()
 
-	if req.Generation != nil && req.Generation.Temperature != nil {
-		data = append(data, fmt.Sprintf("%f", *req.Generation.Temperature))
+	stats := newCompletionStats(format, params)
+	resp, err := e.backends.Complete(ctx, params, e.routedModels(params, format)...)
+	if err != nil {
+		return nil, nil, err
 	}
+	stats.addUsage(resp.Usage)
+	stats.stop()
+	return resp, stats, nil
+}
 
+// routedModels returns the model chain by the format route or by the input kind,
+// requests with images need a vision model
+func (e *ExplainService) routedModels(params *openai.ChatCompletionNewParams, format string) []string {
+	return e.routes.Models(format, countImages(params.Messages) > 0)
+}
+
+func sendOrStop(ctx context.Context, ch chan<- models.StreamChunk, msg models.StreamChunk) bool {
+	select {
+	case ch <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func stageChunk(name string) models.StreamChunk {
+	return models.StreamChunk{Stage: &models.StreamStage{Name: name}}
+}
+
+// getCacheKey derives a content-addressed key "<namespace>:explain:<version>:<file hash>:<request hash>":
+// the request hash covers the file, its name sent in the user prompt, format, model, prompt templates and generation params
+func (e *ExplainService) getCacheKey(req *models.ExplainRequest) string {
+	fileHash := req.FileHash()
+	return fmt.Sprintf("%s%s:%s:%s", e.cachePrefix, e.cacheVersion, fileHash, e.hashRequest(req, fileHash, true))
+}
+
+// hashRequest hashes everything the answer depends on, the semantic cache leaves the prompt out
+func (e *ExplainService) hashRequest(req *models.ExplainRequest, fileHash string, withPrompt bool) string {
+	h := sha256.New()
+	write := func(field, value string) {
+		// length prefix keeps neighbouring fields from colliding
+		fmt.Fprintf(h, "%s:%d:%s;", field, len(value), value)
+	}
+
+	write("file", fileHash)
+	write("file_name", req.FileName)
+	write("format", req.FileFormat)
+	write("model", e.modelName)
+	write("vision_models", strings.Join(e.routes.Models(req.FileFormat, true), "|"))
+	write("text_models", strings.Join(e.routes.Models(req.FileFormat, false), "|"))
+	write("prompt_version", promptVersion)
+	write("output", req.Output)
+	write("system_prompt", systemPrompt(req))
+	write("user_template", userPromptTemplate)
+	if withPrompt {
+		write("prompt", req.Prompt)
+	}
+
+	temperature, maxTokens := "default", "default"
+	if req.Generation != nil && req.Generation.Temperature != nil {
+		temperature = strconv.FormatFloat(*req.Generation.Temperature, 'g', -1, 64)
+	}
 	if req.Generation != nil && req.Generation.MaxTokens != nil {
-		data = append(data, fmt.Sprintf("%d", *req.Generation.MaxTokens))
+		maxTokens = strconv.Itoa(*req.Generation.MaxTokens)
 	}
+	write("temperature", temperature)
+	write("max_tokens", maxTokens)
 
-	hash := sha256.Sum256([]byte(strings.Join(data, "-")))
-	return hex.EncodeToString(hash[:])
+	return hex.EncodeToString(h.Sum(nil))
 }
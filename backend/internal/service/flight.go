@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/admission"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+)
+
+// flight is a call shared by identical requests. Every caller reads all items
+// from the start, the call is cancelled only when all callers are gone
+type flight[T any] struct {
+	key string
+
+	mu      sync.Mutex
+	items   []T
+	done    bool
+	wake    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup runs one call per key at a time
+type flightGroup[T any] struct {
+	name string
+
+	mu      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+func newFlightGroup[T any](name string) *flightGroup[T] {
+	return &flightGroup[T]{
+		name:    name,
+		flights: make(map[string]*flight[T]),
+	}
+}
+
+// join returns the running flight of key or starts run in a new one. Calls of different
+// admission priorities don't share a flight, so an interactive request never waits behind
+// batch calls. The call context keeps values and the deadline of ctx but not its cancellation.
+// leave must be called when the caller is done
+func (g *flightGroup[T]) join(ctx context.Context, key string, run func(ctx context.Context, emit func(T))) *flight[T] {
+	key = fmt.Sprintf("%s:%s", key, admission.PriorityFrom(ctx))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f, ok := g.flights[key]; ok {
+		f.mu.Lock()
+		f.waiters++
+		f.mu.Unlock()
+
+		metrics.DedupRequest(g.name)
+		return f
+	}
+
+	callCtx := context.WithoutCancel(ctx)
+	stop := func() {}
+	if deadline, ok := ctx.Deadline(); ok {
+		callCtx, stop = context.WithDeadline(callCtx, deadline)
+	}
+	callCtx, cancel := context.WithCancel(callCtx)
+	f := &flight[T]{
+		key:     key,
+		wake:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+	}
+	g.flights[key] = f
+
+	go func() {
+		defer stop()
+		defer cancel()
+		run(callCtx, f.emit)
+
+		g.mu.Lock()
+		if g.flights[key] == f {
+			delete(g.flights, key)
+		}
+		g.mu.Unlock()
+		f.finish()
+	}()
+	return f
+}
+
+// leave cancels the call when the last caller is gone
+func (g *flightGroup[T]) leave(f *flight[T]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 || f.done {
+		return
+	}
+	// new requests start their own call instead of joining the cancelled one
+	if g.flights[f.key] == f {
+		delete(g.flights, f.key)
+	}
+	f.cancel()
+}
+
+// read returns items starting at from, waiting for new ones. done is set when
+// the call has finished and all items are returned
+func (f *flight[T]) read(ctx context.Context, from int) (items []T, done bool, err error) {
+	for {
+		f.mu.Lock()
+		items, done, wake := f.items[from:], f.done, f.wake
+		f.mu.Unlock()
+
+		if len(items) > 0 || done {
+			return items, done, nil
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+func (f *flight[T]) emit(item T) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.items = append(f.items, item)
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+func (f *flight[T]) finish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.done = true
+	close(f.wake)
+}
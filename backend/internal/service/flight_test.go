@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/admission"
+)
+
+// readAll reads the items of f until the call is done
+func readAll(t *testing.T, f *flight[int]) []int {
+	t.Helper()
+	var all []int
+	for {
+		items, done, err := f.read(context.Background(), len(all))
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		all = append(all, items...)
+		if done {
+			return all
+		}
+	}
+}
+
+func TestFlightShared(t *testing.T) {
+	g := newFlightGroup[int]("test")
+	release := make(chan struct{})
+	runs := 0
+	run := func(ctx context.Context, emit func(int)) {
+		runs++
+		emit(1)
+		<-release
+		emit(2)
+	}
+
+	leader := g.join(context.Background(), "key", run)
+	if items, _, _ := leader.read(context.Background(), 0); !slices.Equal(items, []int{1}) {
+		t.Fatalf("leader items = %v, want [1]", items)
+	}
+	// the follower gets the items emitted before it joined
+	follower := g.join(context.Background(), "key", run)
+	if follower != leader {
+		t.Fatal("identical call started its own flight")
+	}
+	close(release)
+
+	for _, f := range []*flight[int]{leader, follower} {
+		if got := readAll(t, f); !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("items = %v, want [1 2]", got)
+		}
+	}
+	g.leave(leader)
+	g.leave(follower)
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+
+	// a finished flight isn't joined again
+	next := g.join(context.Background(), "key", func(ctx context.Context, emit func(int)) { emit(3) })
+	defer g.leave(next)
+	if got := readAll(t, next); !slices.Equal(got, []int{3}) {
+		t.Errorf("items after the call finished = %v, want [3]", got)
+	}
+}
+
+func TestFlightCancelledWhenAllLeave(t *testing.T) {
+	g := newFlightGroup[int]("test")
+	cancelled := make(chan struct{})
+	run := func(ctx context.Context, emit func(int)) {
+		<-ctx.Done()
+		close(cancelled)
+	}
+
+	// the caller context is cancelled but the call only ends when the last caller leaves
+	ctx, cancel := context.WithCancel(context.Background())
+	first := g.join(ctx, "key", run)
+	cancel()
+	second := g.join(context.Background(), "key", run)
+
+	g.leave(first)
+	select {
+	case <-cancelled:
+		t.Fatal("call cancelled while a caller is left")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	g.leave(second)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("call not cancelled after all callers left")
+	}
+	readAll(t, first)
+
+	// new callers don't join the cancelled call
+	fresh := g.join(context.Background(), "key", func(ctx context.Context, emit func(int)) { emit(1) })
+	defer g.leave(fresh)
+	if fresh == first {
+		t.Fatal("joined a cancelled flight")
+	}
+}
+
+func TestFlightSplitByPriority(t *testing.T) {
+	g := newFlightGroup[int]("test")
+	release := make(chan struct{})
+	run := func(ctx context.Context, emit func(int)) {
+		emit(int(admission.PriorityFrom(ctx)))
+		<-release
+	}
+	defer close(release)
+
+	batch := g.join(admission.WithPriority(context.Background(), admission.PriorityBatch), "key", run)
+	defer g.leave(batch)
+	interactive := g.join(context.Background(), "key", run)
+	defer g.leave(interactive)
+
+	if batch == interactive {
+		t.Fatal("interactive call joined a batch flight")
+	}
+	if items, _, _ := interactive.read(context.Background(), 0); !slices.Equal(items, []int{int(admission.PriorityInteractive)}) {
+		t.Errorf("interactive flight runs with priority %v", items)
+	}
+}
+
+func TestFlightKeepsDeadline(t *testing.T) {
+	g := newFlightGroup[int]("test")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	want, _ := ctx.Deadline()
+
+	f := g.join(ctx, "key", func(ctx context.Context, emit func(int)) {
+		if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(want) {
+			emit(0)
+			return
+		}
+		<-ctx.Done()
+		emit(1)
+	})
+	defer g.leave(f)
+
+	if got := readAll(t, f); !slices.Equal(got, []int{1}) {
+		t.Fatal("call doesn't run within the caller deadline")
+	}
+}
@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/admission"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobQueueFull = errors.New("job queue is full")
+
+	errJobCancelled = errors.New("job cancelled")
+	errShutdown     = errors.New("interrupted by backend shutdown")
+	errInterrupted  = errors.New("interrupted by backend restart")
+	errIncomplete   = errors.New("incomplete stream: generation ended without the final chunk")
+)
+
+const (
+	jobSaveTimeout      = 5 * time.Second
+	jobRecoverTimeout   = 30 * time.Second
+	jobProgressInterval = time.Second
+	defaultMaxTokens    = 512
+)
+
+type streamExplainer interface {
+	SendStream(ctx context.Context, req *models.ExplainRequest) (<-chan models.StreamChunk, error)
+}
+
+// jobStore keeps job state, Keys lists jobs left unfinished by a previous run
+type jobStore interface {
+	Cache
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+type jobTask struct {
+	id     string
+	req    *models.ExplainRequest
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// JobService runs explanations in a bounded worker pool and keeps their state in the store,
+// so finished results outlive the backend process. Job ids start with the instance name,
+// jobs of this instance left queued or running are marked failed on start
+type JobService struct {
+	logger    *log.Logger
+	explainer streamExplainer
+	store     jobStore
+	keyPrefix string
+	instance  string
+	timeout   time.Duration
+
+	tasks chan *jobTask
+	ctx   context.Context
+	stop  context.CancelCauseFunc
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	active map[string]*jobTask
+	closed bool
+}
+
+// NewJobService keeps jobs under "<namespace>:job:<instance>_<id>" keys
+func NewJobService(logger *log.Logger, explainer streamExplainer, store jobStore, namespace string, cfg config.JobsConfig) *JobService {
+	ctx, stop := context.WithCancelCause(context.Background())
+	s := &JobService{
+		logger:    logger,
+		explainer: explainer,
+		store:     store,
+		keyPrefix: fmt.Sprintf("%s:%s:", namespace, jobKeyKind),
+		instance:  cfg.Instance,
+		timeout:   cfg.Timeout,
+		tasks:     make(chan *jobTask, cfg.QueueSize),
+		ctx:       ctx,
+		stop:      stop,
+		active:    make(map[string]*jobTask),
+	}
+	s.recover()
+
+	for i := 0; i < cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Submit rejects unsupported formats before the job is queued, the job resolves the format itself
+func (s *JobService) Submit(ctx context.Context, req *models.ExplainRequest) (*models.Job, error) {
+	format, _ := resolvedFormat(req)
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	id = s.instance + "_" + id
+
+	now := time.Now().UTC()
+	job := &models.Job{
+		ID:        id,
+		Status:    models.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.save(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	taskCtx, cancel := context.WithCancelCause(s.ctx)
+	task := &jobTask{id: id, req: req, ctx: taskCtx, cancel: cancel}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		cancel(errShutdown)
+		s.finish(job, models.JobFailed, nil, errShutdown)
+		return nil, errShutdown
+	}
+
+	select {
+	case s.tasks <- task:
+		s.active[id] = task
+	default:
+		cancel(ErrJobQueueFull)
+		s.finish(job, models.JobFailed, nil, ErrJobQueueFull)
+		return nil, ErrJobQueueFull
+	}
+
+	s.logger.Printf("job %s queued\n", id)
+	return job, nil
+}
+
+func (s *JobService) Get(ctx context.Context, id string) (*models.Job, error) {
+	raw, found, err := s.store.Get(ctx, s.jobKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+	if !found {
+		return nil, ErrJobNotFound
+	}
+
+	var job models.Job
+	if err := sonic.UnmarshalString(raw, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// Cancel stops a queued or running job. Finished jobs are returned unchanged
+func (s *JobService) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Finished() {
+		return job, nil
+	}
+
+	if task, ok := s.active[id]; ok {
+		task.cancel(errJobCancelled)
+		delete(s.active, id)
+	}
+
+	s.finish(job, models.JobCancelled, nil, nil)
+	s.logger.Printf("job %s cancelled\n", id)
+	return job, nil
+}
+
+// Close stops the workers and marks unfinished jobs as failed
+func (s *JobService) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.stop(errShutdown)
+	s.wg.Wait()
+
+	for {
+		select {
+		case task := <-s.tasks:
+			s.completeTask(task, nil, errShutdown)
+		default:
+			return
+		}
+	}
+}
+
+func (s *JobService) worker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case task := <-s.tasks:
+			s.run(task)
+		}
+	}
+}
+
+func (s *JobService) run(task *jobTask) {
+	if task.ctx.Err() != nil {
+		s.completeTask(task, nil, context.Cause(task.ctx))
+		return
+	}
+
+	job, err := s.Get(task.ctx, task.id)
+	if err != nil {
+		s.completeTask(task, nil, err)
+		return
+	}
+
+	job.Status = models.JobRunning
+	job.Progress = 0.05
+	s.progress(task, job)
+
+	ctx, cancel := context.WithTimeout(task.ctx, s.timeout)
+	defer cancel()
+
+	maxTokens := defaultMaxTokens
+	if task.req.Generation != nil && task.req.Generation.MaxTokens != nil && *task.req.Generation.MaxTokens > 0 {
+		maxTokens = *task.req.Generation.MaxTokens
+	}
+
+	// jobs are not waited for interactively, so they yield to streams in the model queue
+	stream, err := s.explainer.SendStream(admission.WithPriority(ctx, admission.PriorityBatch), task.req)
+	if err != nil {
+		s.completeTask(task, nil, err)
+		return
+	}
+
+	var (
+		builder    strings.Builder
+		formatInfo models.FormatInfo
+		structured *models.StructuredExplanation
+		usage      *models.StreamStage
+		deltas     int
+		done       bool
+		lastSaved  = time.Now()
+	)
+
+	for chunk := range stream {
+		if chunk.Err != nil {
+			s.completeTask(task, nil, chunk.Err)
+			return
+		}
+		if chunk.Meta != nil {
+			formatInfo = chunk.Meta.FormatInfo
+			continue
+		}
+		if chunk.Stage != nil {
+			if chunk.Stage.Name == models.StageUsage {
+				usage = chunk.Stage
+			}
+			continue
+		}
+		if chunk.Structured != nil {
+			structured = chunk.Structured
+		}
+		done = done || chunk.Done
+		builder.WriteString(chunk.Delta)
+		deltas++
+
+		// deltas are roughly tokens, so progress is measured against the token budget
+		if time.Since(lastSaved) >= jobProgressInterval {
+			job.Progress = 0.1 + 0.85*min(1, float64(deltas)/float64(maxTokens))
+			s.progress(task, job)
+			lastSaved = time.Now()
+		}
+	}
+
+	if err := context.Cause(ctx); err != nil {
+		s.completeTask(task, nil, err)
+		return
+	}
+	if !done {
+		s.completeTask(task, nil, errIncomplete)
+		return
+	}
+
+	result := &models.ExplainResponse{
+		Explanation: builder.String(),
+		Structured:  structured,
+		FormatInfo:  formatInfo,
+	}
+	if usage != nil {
+		result.Model, result.Usage, result.Latency = usage.Model, usage.Usage, usage.Latency
+	}
+	s.completeTask(task, result, nil)
+}
+
+func (s *JobService) completeTask(task *jobTask, result *models.ExplainResponse, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.active, task.id)
+	task.cancel(nil)
+
+	// cancelled jobs are already saved by Cancel
+	if errors.Is(context.Cause(task.ctx), errJobCancelled) {
+		return
+	}
+
+	job, getErr := s.Get(context.Background(), task.id)
+	if getErr != nil {
+		s.logger.Printf("job %s: %v\n", task.id, getErr)
+		return
+	}
+
+	status := models.JobDone
+	if err != nil {
+		status = models.JobFailed
+		s.logger.Printf("job %s failed: %v\n", task.id, err)
+	} else {
+		s.logger.Printf("job %s done\n", task.id)
+	}
+	s.finish(job, status, result, err)
+}
+
+func (s *JobService) finish(job *models.Job, status models.JobStatus, result *models.ExplainResponse, err error) {
+	job.Status = status
+	job.Result = result
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if status == models.JobDone {
+		job.Progress = 1
+	}
+	s.update(job)
+}
+
+// progress saves a running job unless it was cancelled or stopped meanwhile. It holds the lock
+// of Cancel, so a late progress write can't turn a cancelled job back into running
+func (s *JobService) progress(task *jobTask, job *models.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task.ctx.Err() != nil {
+		return
+	}
+	s.update(job)
+}
+
+// recover marks jobs left queued or running by a crashed or killed process of this instance
+// as failed, jobs of other instances sharing the store keep running
+func (s *JobService) recover() {
+	ctx, cancel := context.WithTimeout(context.Background(), jobRecoverTimeout)
+	defer cancel()
+
+	keys, err := s.store.Keys(ctx, s.jobKey(s.instance+"_"))
+	if err != nil {
+		s.logger.Printf("failed to list jobs: %v\n", err)
+		return
+	}
+
+	recovered := 0
+	for _, key := range keys {
+		job, err := s.Get(ctx, strings.TrimPrefix(key, s.keyPrefix))
+		if err != nil {
+			if !errors.Is(err, ErrJobNotFound) {
+				s.logger.Printf("%s: %v\n", key, err)
+			}
+			continue
+		}
+		if job.Finished() {
+			continue
+		}
+		s.finish(job, models.JobFailed, nil, errInterrupted)
+		recovered++
+	}
+	if recovered > 0 {
+		s.logger.Printf("%d unfinished jobs of the previous run marked as failed\n", recovered)
+	}
+}
+
+// update saves job state independently of the job context, which may already be cancelled
+func (s *JobService) update(job *models.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobSaveTimeout)
+	defer cancel()
+
+	if err := s.save(ctx, job); err != nil {
+		s.logger.Printf("failed to save job %s: %v\n", job.ID, err)
+	}
+}
+
+func (s *JobService) save(ctx context.Context, job *models.Job) error {
+	job.UpdatedAt = time.Now().UTC()
+	raw, err := sonic.MarshalString(job)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(ctx, s.jobKey(job.ID), raw)
+}
+
+func (s *JobService) jobKey(id string) string {
+	return s.keyPrefix + id
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// newTestJobService has no workers, tests run the queued tasks themselves
+func newTestJobService(t *testing.T, store jobStore) *JobService {
+	return newTestJobServiceOf(t, store, "a")
+}
+
+func newTestJobServiceOf(t *testing.T, store jobStore, instance string) *JobService {
+	s := NewJobService(log.New(io.Discard, "", 0), nil, store, "test", config.JobsConfig{
+		Instance:  instance,
+		QueueSize: 10,
+		Timeout:   time.Minute,
+		TTL:       time.Hour,
+	})
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestJobSubmitRejectsUnsupportedFormat(t *testing.T) {
+	store := cache.NewMemoryStore(1<<20, time.Hour)
+	s := newTestJobService(t, store)
+
+	req := &models.ExplainRequest{FileName: "file.docx", FileFormat: "docx", FileData: []byte{0, 1, 2}}
+	if _, err := s.Submit(context.Background(), req); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("Submit error = %v, want ErrUnsupportedFormat", err)
+	}
+	if keys, _ := store.Keys(context.Background(), ""); len(keys) != 0 {
+		t.Fatalf("rejected job was saved: %v", keys)
+	}
+	if req.FileFormat != "docx" {
+		t.Fatalf("Submit changed FileFormat to %q", req.FileFormat)
+	}
+}
+
+func TestJobRunCompletesWithoutRecord(t *testing.T) {
+	store := cache.NewMemoryStore(1<<20, time.Hour)
+	s := newTestJobService(t, store)
+
+	job, err := s.Submit(context.Background(), &models.ExplainRequest{FileName: "a.txt", FileData: []byte("A -> B")})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	// the record expired or the store failed before the job started
+	if _, err := store.Delete(context.Background(), s.jobKey(job.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	task := <-s.tasks
+	s.run(task)
+
+	s.mu.Lock()
+	_, active := s.active[job.ID]
+	s.mu.Unlock()
+	if active {
+		t.Fatal("job left active after its record failed to load")
+	}
+	if task.ctx.Err() == nil {
+		t.Fatal("task context was not released")
+	}
+}
+
+func TestJobRecoverOwnInstance(t *testing.T) {
+	store := cache.NewMemoryStore(1<<20, time.Hour)
+	req := &models.ExplainRequest{FileName: "a.txt", FileData: []byte("A -> B")}
+
+	a := newTestJobServiceOf(t, store, "a")
+	jobA, err := a.Submit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	b := newTestJobServiceOf(t, store, "b")
+	jobB, err := b.Submit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// instance "a" restarts while "b" keeps running its job
+	newTestJobServiceOf(t, store, "a")
+
+	if got, _ := b.Get(context.Background(), jobA.ID); got.Status != models.JobFailed {
+		t.Errorf("job of the restarted instance = %s, want %s", got.Status, models.JobFailed)
+	}
+	if got, _ := a.Get(context.Background(), jobB.ID); got.Status != models.JobQueued {
+		t.Errorf("job of another instance = %s, want %s", got.Status, models.JobQueued)
+	}
+}
+
+// chunkExplainer streams the given chunks and closes the stream
+type chunkExplainer []models.StreamChunk
+
+func (c chunkExplainer) SendStream(_ context.Context, _ *models.ExplainRequest) (<-chan models.StreamChunk, error) {
+	ch := make(chan models.StreamChunk, len(c))
+	for _, chunk := range c {
+		ch <- chunk
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestJobRunRequiresFinalChunk(t *testing.T) {
+	tests := map[string]struct {
+		chunks     chunkExplainer
+		status     models.JobStatus
+		wantResult string
+	}{
+		"complete": {
+			chunks:     chunkExplainer{{Delta: "A calls "}, {Delta: "B"}, {Done: true}},
+			status:     models.JobDone,
+			wantResult: "A calls B",
+		},
+		"cut off": {
+			chunks: chunkExplainer{{Delta: "A calls "}},
+			status: models.JobFailed,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestJobService(t, cache.NewMemoryStore(1<<20, time.Hour))
+			s.explainer = tc.chunks
+
+			job, err := s.Submit(context.Background(), &models.ExplainRequest{FileName: "a.txt", FileData: []byte("A -> B")})
+			if err != nil {
+				t.Fatalf("Submit: %v", err)
+			}
+			s.run(<-s.tasks)
+
+			got, err := s.Get(context.Background(), job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tc.status {
+				t.Fatalf("status = %s, want %s (error %q)", got.Status, tc.status, got.Error)
+			}
+			if tc.status == models.JobDone && (got.Result == nil || got.Result.Explanation != tc.wantResult || got.Progress != 1) {
+				t.Fatalf("result = %+v, progress %v", got.Result, got.Progress)
+			}
+			if tc.status == models.JobFailed && (got.Result != nil || got.Progress == 1) {
+				t.Fatalf("cut off job kept result %+v, progress %v", got.Result, got.Progress)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// renderedFormats run external converters or rasterization, only their preprocessing is cached
+var renderedFormats = map[string]bool{
+	DRAWIO: true,
+	BPMN:   true,
+	SVG:    true,
+	PDF:    true,
+}
+
+// preprocessed is the model input of a file without the prompts: text extracted from
+// the file and rendered images as data URLs. It doesn't depend on the prompt, so it is
+// cached apart from the answers
+type preprocessed struct {
+	Text   string   `json:"text,omitempty"`
+	Images []string `json:"images,omitempty"`
+	Pages  int      `json:"pages"`
+}
+
+// SetPreprocessCache caches preprocessing of rendered formats in the answer cache,
+// outputs above maxBytes are not stored. Requires SetCacheClient
+func (e *ExplainService) SetPreprocessCache(maxBytes int) {
+	e.preprocessMaxBytes = maxBytes
+}
+
+// preprocess returns the cached model input of the file or preprocesses it, cached reports a cache hit
+func (e *ExplainService) preprocess(ctx context.Context, req *models.ExplainRequest) (input *preprocessed, cached bool, err error) {
+	if e.cache == nil || e.preprocessMaxBytes <= 0 || !renderedFormats[req.FileFormat] {
+		input, err = e.limitedPreprocess(ctx, req)
+		return input, false, err
+	}
+
+	key := e.preprocessKey(req)
+	if input, ok := e.cachedPreprocess(ctx, key); ok {
+		e.logger.Printf("preprocessing of %s served from cache\n", req.FileName)
+		metrics.FilePreprocessTotal("cached", req.FileFormat)
+		return input, true, nil
+	}
+
+	input, err = e.limitedPreprocess(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := sonic.MarshalString(input)
+	if err == nil && len(value) <= e.preprocessMaxBytes {
+		if err := e.cache.Set(ctx, key, value); err != nil {
+			e.logger.Printf("failed to set preprocess cache: %v\n", err)
+		}
+	}
+	return input, false, nil
+}
+
+// limitedPreprocess waits for a free rendering slot before preprocessing rendered formats,
+// converters and rasterization are too heavy to run for every upload at once
+func (e *ExplainService) limitedPreprocess(ctx context.Context, req *models.ExplainRequest) (*preprocessed, error) {
+	if !renderedFormats[req.FileFormat] {
+		return e.runPreprocess(req)
+	}
+
+	select {
+	case e.renderSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-e.renderSlots }()
+	return e.runPreprocess(req)
+}
+
+func (e *ExplainService) cachedPreprocess(ctx context.Context, key string) (*preprocessed, bool) {
+	raw, found, err := e.cache.Get(ctx, key)
+	if err != nil {
+		e.logger.Printf("preprocess cache get error: %v\n", err)
+	}
+	if !found {
+		return nil, false
+	}
+
+	var input preprocessed
+	if err := sonic.UnmarshalString(raw, &input); err != nil {
+		e.logger.Printf("invalid preprocess cache entry: %v\n", err)
+		return nil, false
+	}
+	return &input, true
+}
+
+// preprocessKey is "<namespace>:preprocess:<version>:<file hash>:<settings hash>", the settings
+// are everything preprocessing depends on besides the file
+func (e *ExplainService) preprocessKey(req *models.ExplainRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "format:%s;prompt_version:%s;dpi:%d;jpeg_quality:%d;", req.FileFormat, promptVersion, dpi, jpegQuality)
+	return fmt.Sprintf("%s:%s:%s:%s:%s", e.cacheNamespace, preprocessKeyKind, e.cacheVersion, req.FileHash(), hex.EncodeToString(h.Sum(nil)))
+}
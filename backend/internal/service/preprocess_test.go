@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+const testBpmn = `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" id="D">
+<bpmn:process id="P"><bpmn:task id="T" name="Take order"/></bpmn:process>
+</bpmn:definitions>`
+
+func newTestPreprocessExplainer(t *testing.T, maxBytes int) (*ExplainService, *cache.MemoryCache) {
+	store := cache.NewMemoryCache(1<<20, time.Hour)
+	e := newTestExplainer(t, slowQueue{}, "answer")
+	e.SetCacheClient(store, "ns", "v1", 0)
+	e.SetPreprocessCache(maxBytes)
+	return e, store
+}
+
+func preprocessRequest(format, data, prompt string) *models.ExplainRequest {
+	return &models.ExplainRequest{FileName: "a." + format, FileFormat: format, FileData: []byte(data), Prompt: prompt}
+}
+
+func TestPreprocessCache(t *testing.T) {
+	tests := map[string]struct {
+		format   string
+		data     string
+		maxBytes int
+		cached   bool
+	}{
+		"rendered format":  {format: BPMN, data: testBpmn, maxBytes: 1 << 20, cached: true},
+		"text format":      {format: TXT, data: "A -> B", maxBytes: 1 << 20},
+		"above the limit":  {format: BPMN, data: testBpmn, maxBytes: 10},
+		"cache turned off": {format: BPMN, data: testBpmn},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, store := newTestPreprocessExplainer(t, tc.maxBytes)
+
+			first, cached, err := e.preprocess(context.Background(), preprocessRequest(tc.format, tc.data, "explain"))
+			if err != nil || cached {
+				t.Fatalf("first preprocess: cached %v, err %v", cached, err)
+			}
+			// the input doesn't depend on the prompt
+			second, cached, err := e.preprocess(context.Background(), preprocessRequest(tc.format, tc.data, "list the tasks"))
+			if err != nil {
+				t.Fatalf("second preprocess: %v", err)
+			}
+			if cached != tc.cached {
+				t.Errorf("cached = %v, want %v", cached, tc.cached)
+			}
+			if second.Text != first.Text || second.Pages != first.Pages {
+				t.Errorf("cached input = %+v, want %+v", second, first)
+			}
+
+			keys, _ := store.Keys(context.Background(), "ns:preprocess:")
+			if stored := len(keys) > 0; stored != tc.cached {
+				t.Errorf("stored keys = %v", keys)
+			}
+		})
+	}
+}
+
+func TestPreprocessInvalidEntry(t *testing.T) {
+	e, store := newTestPreprocessExplainer(t, 1<<20)
+	req := preprocessRequest(BPMN, testBpmn, "explain")
+	if err := store.Set(context.Background(), e.preprocessKey(req), "not json"); err != nil {
+		t.Fatal(err)
+	}
+
+	input, cached, err := e.preprocess(context.Background(), req)
+	if err != nil || cached {
+		t.Fatalf("preprocess: cached %v, err %v", cached, err)
+	}
+	if !strings.Contains(input.Text, "Take order") {
+		t.Errorf("text = %q, want the parsed process", input.Text)
+	}
+}
+
+func TestPreprocessKey(t *testing.T) {
+	e, _ := newTestPreprocessExplainer(t, 1<<20)
+	key := e.preprocessKey(preprocessRequest(BPMN, testBpmn, "explain"))
+
+	if other := e.preprocessKey(preprocessRequest(BPMN, testBpmn, "list the tasks")); other != key {
+		t.Error("key depends on the prompt")
+	}
+	if other := e.preprocessKey(preprocessRequest(SVG, testBpmn, "explain")); other == key {
+		t.Error("key doesn't depend on the format")
+	}
+	if other := e.preprocessKey(preprocessRequest(BPMN, testBpmn+" ", "explain")); other == key {
+		t.Error("key doesn't depend on the file")
+	}
+	if !strings.HasPrefix(key, "ns:preprocess:v1:") {
+		t.Errorf("key = %q", key)
+	}
+}
+
+func TestPreprocessWaitsForRenderSlot(t *testing.T) {
+	e, _ := newTestPreprocessExplainer(t, 0)
+	for range cap(e.renderSlots) {
+		e.renderSlots <- struct{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := e.preprocess(ctx, preprocessRequest(BPMN, testBpmn, "explain")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("preprocess error = %v, want to wait for a render slot", err)
+	}
+	// text formats aren't rendered
+	if _, _, err := e.preprocess(ctx, preprocessRequest(TXT, "A -> B", "explain")); err != nil {
+		t.Fatalf("text preprocess: %v", err)
+	}
+}
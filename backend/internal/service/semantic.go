@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+type embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+type semanticCache struct {
+	embedder   embedder
+	threshold  float64
+	maxPrompts int
+}
+
+// semanticIndex lists prompts answered about one file with the same request settings,
+// Key points to the cached answer
+type semanticIndex struct {
+	Prompts []semanticPrompt `json:"prompts"`
+}
+
+type semanticPrompt struct {
+	Prompt    string    `json:"prompt"`
+	Embedding []float32 `json:"embedding"`
+	Key       string    `json:"key"`
+}
+
+// semanticQuery is an embedded prompt which missed the cache, its answer is indexed once cached
+type semanticQuery struct {
+	indexKey string
+	prompt   semanticPrompt
+}
+
+// SetSemanticCache reuses answers to prompts with a cosine similarity of at least threshold,
+// maxPrompts last prompts are kept per file and request settings. Requires SetCacheClient
+func (e *ExplainService) SetSemanticCache(embedder embedder, threshold float64, maxPrompts int) {
+	e.semantic = &semanticCache{
+		embedder:   embedder,
+		threshold:  threshold,
+		maxPrompts: max(maxPrompts, 1),
+	}
+}
+
+// cached looks up the exact key, then an answer to a similar prompt. similarity is set for semantic hits only.
+// On a miss query keeps the embedded prompt to index the answer, it is nil without the semantic cache
+func (e *ExplainService) cached(ctx context.Context, req *models.ExplainRequest, key string) (value string, similarity float64, query *semanticQuery, found bool) {
+	if e.cache == nil {
+		return "", 0, nil, false
+	}
+	if value, found := e.lookup(ctx, key); found {
+		return value, 0, nil, true
+	}
+	if e.semantic == nil || strings.TrimSpace(req.Prompt) == "" {
+		return "", 0, nil, false
+	}
+	return e.semanticLookup(ctx, req)
+}
+
+func (e *ExplainService) semanticLookup(ctx context.Context, req *models.ExplainRequest) (string, float64, *semanticQuery, bool) {
+	embedding, err := e.semantic.embedder.Embed(ctx, req.Prompt)
+	if err != nil {
+		metrics.SemanticCacheRequest("error")
+		e.logger.Printf("semantic cache: %v\n", err)
+		return "", 0, nil, false
+	}
+
+	query := &semanticQuery{
+		indexKey: e.semanticKey(req),
+		prompt:   semanticPrompt{Prompt: req.Prompt, Embedding: embedding},
+	}
+	index := e.semanticIndex(ctx, query.indexKey)
+
+	var (
+		best       *semanticPrompt
+		similarity float64
+	)
+	for i, prompt := range index.Prompts {
+		if s, ok := cosine(embedding, prompt.Embedding); ok && (best == nil || s > similarity) {
+			best, similarity = &index.Prompts[i], s
+		}
+	}
+	if best != nil {
+		metrics.SemanticCacheSimilarity(similarity)
+	}
+
+	if best != nil && similarity >= e.semantic.threshold {
+		// the answer may be purged or expired before its index
+		if value, found, err := e.cache.Get(ctx, best.Key); err == nil && found {
+			metrics.SemanticCacheRequest("hit")
+			e.logger.Printf("semantic cache: prompt %q matched %q with similarity %.3f\n", req.Prompt, best.Prompt, similarity)
+			return value, similarity, nil, true
+		}
+	}
+
+	metrics.SemanticCacheRequest("miss")
+	return "", 0, query, false
+}
+
+// semanticAdd indexes the cached answer of query, the oldest prompts are dropped above maxPrompts.
+// Concurrent additions to one index may overwrite each other, which only costs future hits
+func (e *ExplainService) semanticAdd(ctx context.Context, query *semanticQuery, answerKey string) {
+	if query == nil {
+		return
+	}
+
+	index := e.semanticIndex(ctx, query.indexKey)
+	prompts := make([]semanticPrompt, 0, len(index.Prompts)+1)
+	for _, prompt := range index.Prompts {
+		if prompt.Key != answerKey {
+			prompts = append(prompts, prompt)
+		}
+	}
+	query.prompt.Key = answerKey
+	prompts = append(prompts, query.prompt)
+	if len(prompts) > e.semantic.maxPrompts {
+		prompts = prompts[len(prompts)-e.semantic.maxPrompts:]
+	}
+
+	data, err := sonic.MarshalString(semanticIndex{Prompts: prompts})
+	if err != nil {
+		return
+	}
+	if err := e.cache.Set(ctx, query.indexKey, data); err != nil {
+		e.logger.Printf("failed to set semantic index: %v\n", err)
+	}
+}
+
+func (e *ExplainService) semanticIndex(ctx context.Context, key string) *semanticIndex {
+	var index semanticIndex
+	raw, found, err := e.cache.Get(ctx, key)
+	if err != nil {
+		e.logger.Printf("semantic index get error: %v\n", err)
+	}
+	if found {
+		if err := sonic.UnmarshalString(raw, &index); err != nil {
+			e.logger.Printf("invalid semantic index: %v\n", err)
+		}
+	}
+	return &index
+}
+
+// semanticKey is "<namespace>:semantic:<version>:<file hash>:<settings hash>", the settings hash
+// is the request hash without the prompt
+func (e *ExplainService) semanticKey(req *models.ExplainRequest) string {
+	fileHash := req.FileHash()
+	return fmt.Sprintf("%s:%s:%s:%s:%s", e.cacheNamespace, semanticKeyKind, e.cacheVersion, fileHash, e.hashRequest(req, fileHash, false))
+}
+
+// cosine returns the dot product of normalized embeddings, embeddings of another model are skipped
+func cosine(a, b []float32) (float64, bool) {
+	if len(a) != len(b) {
+		return 0, false
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot, true
+}
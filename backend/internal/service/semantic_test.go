@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// staticEmbedder embeds prompts by the table
+type staticEmbedder map[string][]float32
+
+func (e staticEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	if embedding, ok := e[text]; ok {
+		return embedding, nil
+	}
+	return nil, fmt.Errorf("no embedding for %q", text)
+}
+
+var testEmbeddings = staticEmbedder{
+	"explain the diagram":      {1, 0},
+	"explain this diagram":     {1, 0},
+	"describe the diagram":     {0.8, 0.6},
+	"list the databases":       {0, 1},
+	"embedded by other model":  {1, 0, 0},
+	"explain the diagram, now": {0.6, 0.8},
+}
+
+func newTestSemanticExplainer(t *testing.T, threshold float64, maxPrompts int) (*ExplainService, *cache.MemoryCache) {
+	store := cache.NewMemoryCache(1<<20, time.Hour)
+	e := newTestExplainer(t, slowQueue{}, "answer")
+	e.SetCacheClient(store, "ns", "v1", 0)
+	e.SetSemanticCache(testEmbeddings, threshold, maxPrompts)
+	return e, store
+}
+
+func semanticRequest(prompt string) *models.ExplainRequest {
+	return &models.ExplainRequest{FileName: "a.txt", FileFormat: TXT, FileData: []byte("A -> B"), Prompt: prompt}
+}
+
+// cacheAnswer caches the answer to prompt and indexes it like a completed request
+func cacheAnswer(t *testing.T, e *ExplainService, store *cache.MemoryCache, req *models.ExplainRequest) string {
+	t.Helper()
+	key := e.getCacheKey(req)
+	if err := store.Set(context.Background(), key, cacheValue(&models.ExplainResponse{Explanation: "answer to " + req.Prompt}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	e.semanticAdd(context.Background(), &semanticQuery{
+		indexKey: e.semanticKey(req),
+		prompt:   semanticPrompt{Prompt: req.Prompt, Embedding: testEmbeddings[req.Prompt]},
+	}, key)
+	return key
+}
+
+func TestSemanticLookupThreshold(t *testing.T) {
+	tests := map[string]struct {
+		indexed    []string
+		threshold  float64
+		prompt     string
+		structured bool
+		similarity float64
+		hit        string
+	}{
+		"same meaning":     {threshold: 0.9, prompt: "explain this diagram", similarity: 1, hit: "explain the diagram"},
+		"at the threshold": {threshold: 0.8, prompt: "describe the diagram", similarity: 0.8, hit: "explain the diagram"},
+		"below threshold":  {threshold: 0.9, prompt: "describe the diagram"},
+		"another question": {threshold: 0.5, prompt: "list the databases"},
+		"another model":    {threshold: 0.5, prompt: "embedded by other model"},
+		"embedding failed": {threshold: 0.5, prompt: "not embedded"},
+		"another output":   {threshold: 0.9, prompt: "explain this diagram", structured: true},
+		"closest prompt": {
+			indexed:   []string{"describe the diagram", "explain the diagram"},
+			threshold: 0.7, prompt: "explain the diagram, now", similarity: 0.96, hit: "describe the diagram",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, store := newTestSemanticExplainer(t, tc.threshold, 10)
+			if tc.indexed == nil {
+				tc.indexed = []string{"explain the diagram"}
+			}
+			answers := map[string]string{}
+			for _, prompt := range tc.indexed {
+				answers[prompt] = cacheAnswer(t, e, store, semanticRequest(prompt))
+			}
+
+			req := semanticRequest(tc.prompt)
+			if tc.structured {
+				req.Output = models.OutputStructured
+			}
+			value, similarity, query, found := e.cached(context.Background(), req, e.getCacheKey(req))
+			if found != (tc.hit != "") {
+				t.Fatalf("found = %v, want %v (similarity %v)", found, tc.hit != "", similarity)
+			}
+			if !found {
+				return
+			}
+			if similarity < tc.similarity-1e-6 || similarity > tc.similarity+1e-6 {
+				t.Errorf("similarity = %v, want %v", similarity, tc.similarity)
+			}
+			if query != nil {
+				t.Error("semantic hit returned a query to index")
+			}
+			if want, _, _ := store.Get(context.Background(), answers[tc.hit]); value != want {
+				t.Errorf("answer = %q, want the answer to %q", value, tc.hit)
+			}
+		})
+	}
+}
+
+func TestSemanticLookupExpiredAnswer(t *testing.T) {
+	e, store := newTestSemanticExplainer(t, 0.9, 10)
+	key := cacheAnswer(t, e, store, semanticRequest("explain the diagram"))
+	// the answer is purged, its index is left
+	if _, err := store.Delete(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	req := semanticRequest("explain this diagram")
+	_, _, query, found := e.cached(context.Background(), req, e.getCacheKey(req))
+	if found {
+		t.Fatal("index of a purged answer served")
+	}
+	if query == nil || query.prompt.Prompt != req.Prompt {
+		t.Fatalf("query = %+v, want the embedded prompt to index", query)
+	}
+}
+
+func TestSemanticAddKeepsLastPrompts(t *testing.T) {
+	e, store := newTestSemanticExplainer(t, 0.9, 2)
+	for _, prompt := range []string{"explain the diagram", "describe the diagram", "list the databases", "describe the diagram"} {
+		cacheAnswer(t, e, store, semanticRequest(prompt))
+	}
+
+	index := e.semanticIndex(context.Background(), e.semanticKey(semanticRequest("")))
+	var prompts []string
+	for _, prompt := range index.Prompts {
+		prompts = append(prompts, prompt.Prompt)
+	}
+	// a prompt answered again moves to the end instead of being indexed twice
+	if len(prompts) != 2 || prompts[0] != "list the databases" || prompts[1] != "describe the diagram" {
+		t.Errorf("indexed prompts = %q", prompts)
+	}
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+const (
+	sessionSaveTimeout = 5 * time.Second
+
+	// rough ratio to fit history into the context without a tokenizer
+	charsPerToken = 4
+)
+
+// SessionService keeps a preprocessed diagram with the message history in the store,
+// so follow-up questions don't re-upload and re-preprocess the file
+type SessionService struct {
+	logger           *log.Logger
+	explainer        *ExplainService
+	store            Cache
+	keyPrefix        string
+	ttl              time.Duration
+	maxHistoryTokens int
+
+	// messages of a session are answered one by one, so every answer sees the previous ones.
+	// Locks are kept while held or waited for and only cover this process
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+// sessionLock is a semaphore of one, waiting for it can be cancelled
+type sessionLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+// NewSessionService keeps sessions under "<namespace>:session:<id>" keys
+func NewSessionService(logger *log.Logger, explainer *ExplainService, store Cache, namespace string, cfg config.SessionsConfig) *SessionService {
+	return &SessionService{
+		logger:           logger,
+		explainer:        explainer,
+		store:            store,
+		keyPrefix:        fmt.Sprintf("%s:%s:", namespace, sessionKeyKind),
+		ttl:              cfg.TTL,
+		maxHistoryTokens: cfg.MaxHistoryTokens,
+		locks:            make(map[string]*sessionLock),
+	}
+}
+
+// Create preprocesses the diagram once and stores its parts for later messages
+func (s *SessionService) Create(ctx context.Context, req *models.ExplainRequest) (*models.SessionCreateResponse, error) {
+	formatInfo := s.explainer.resolveFormat(req)
+	if err := checkFormat(req.FileFormat); err != nil {
+		return nil, err
+	}
+
+	params, _, err := s.explainer.buildOpenAIReq(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:         id,
+		FileName:   req.FileName,
+		FileFormat: req.FileFormat,
+		Parts:      diagramParts(params.Messages),
+		CreatedAt:  now,
+	}
+	if err := s.save(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	s.logger.Printf("session %s created for file %s\n", id, req.FileName)
+	return &models.SessionCreateResponse{
+		ID:         id,
+		ExpiresAt:  now.Add(s.ttl),
+		FormatInfo: formatInfo,
+	}, nil
+}
+
+func (s *SessionService) Send(ctx context.Context, id string, req *models.SessionMessageRequest) (*models.ExplainResponse, error) {
+	unlock, err := s.lock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	session, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	params := s.buildParams(session, req)
+	resp, stats, err := s.explainer.complete(ctx, params, session.FileFormat)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI client error: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("OpenAI client error: empty choices")
+	}
+
+	answer := resp.Choices[0].Message.Content
+	if err := s.appendTurn(ctx, session, req.Content, answer); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return &models.ExplainResponse{
+		Explanation: answer,
+		Model:       stats.model(),
+		Latency:     stats.finish(),
+		Usage:       stats.usage,
+	}, nil
+}
+
+// SendStream answers with ctx of the stream, which outlives the request. Waiting for
+// the previous message of the session ends with waitCtx, the request context
+func (s *SessionService) SendStream(ctx, waitCtx context.Context, id string, req *models.SessionMessageRequest) (<-chan models.StreamChunk, error) {
+	unlock, err := s.lock(waitCtx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.get(ctx, id)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	ch := make(chan models.StreamChunk, 2)
+	go func() {
+		defer unlock()
+		defer close(ch)
+
+		s.explainer.streamCompletion(ctx, s.buildParams(session, req), session.FileFormat, ch, func(answer, _ string, _ []int) {
+			if err := s.appendTurn(ctx, session, req.Content, answer); err != nil {
+				s.logger.Printf("failed to save session %s: %v\n", id, err)
+			}
+		})
+	}()
+
+	return ch, nil
+}
+
+func (s *SessionService) buildParams(session *models.Session, req *models.SessionMessageRequest) *openai.ChatCompletionNewParams {
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(session.Parts))
+	for _, part := range session.Parts {
+		if part.ImageURL != "" {
+			parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: part.ImageURL,
+			}))
+			continue
+		}
+		parts = append(parts, openai.TextContentPart(part.Text))
+	}
+
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(systemPromptSession),
+		openai.UserMessage(parts),
+	}
+	for _, msg := range trimHistory(session.History, s.maxHistoryTokens-estimateTokens(req.Content)) {
+		if msg.Role == models.RoleAssistant {
+			messages = append(messages, openai.AssistantMessage(msg.Content))
+		} else {
+			messages = append(messages, openai.UserMessage(msg.Content))
+		}
+	}
+	messages = append(messages, openai.UserMessage(req.Content))
+
+	params := &openai.ChatCompletionNewParams{
+		Model:    shared.ChatModel(s.explainer.modelName),
+		Messages: messages,
+	}
+	applyGeneration(params, req.Generation)
+	return params
+}
+
+// appendTurn saves the answered question, the store keeps only the turns which still fit the context
+func (s *SessionService) appendTurn(ctx context.Context, session *models.Session, question, answer string) error {
+	session.History = append(session.History,
+		models.SessionMessage{Role: models.RoleUser, Content: question},
+		models.SessionMessage{Role: models.RoleAssistant, Content: answer},
+	)
+	session.History = trimHistory(session.History, s.maxHistoryTokens)
+
+	// the answer is already generated, so it's saved even if the client is gone
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sessionSaveTimeout)
+	defer cancel()
+	return s.save(ctx, session)
+}
+
+func (s *SessionService) get(ctx context.Context, id string) (*models.Session, error) {
+	raw, found, err := s.store.Get(ctx, s.sessionKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if !found {
+		return nil, ErrSessionNotFound
+	}
+
+	var session models.Session
+	if err := sonic.UnmarshalString(raw, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return &session, nil
+}
+
+// save also prolongs the session TTL
+func (s *SessionService) save(ctx context.Context, session *models.Session) error {
+	session.UpdatedAt = time.Now().UTC()
+	raw, err := sonic.MarshalString(session)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(ctx, s.sessionKey(session.ID), raw)
+}
+
+// lock waits until no other message of the session is answered or ctx is done
+func (s *SessionService) lock(ctx context.Context, id string) (unlock func(), err error) {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sessionLock{sem: make(chan struct{}, 1)}
+		s.locks[id] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	select {
+	case l.sem <- struct{}{}:
+		return func() {
+			<-l.sem
+			s.unref(id, l)
+		}, nil
+	case <-ctx.Done():
+		s.unref(id, l)
+		return nil, ctx.Err()
+	}
+}
+
+// unref forgets the lock once nobody holds or waits for it
+func (s *SessionService) unref(id string, l *sessionLock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(s.locks, id)
+	}
+}
+
+// diagramParts keeps content of the user message built by the preprocessing step
+func diagramParts(messages []openai.ChatCompletionMessageParamUnion) []models.SessionPart {
+	var parts []models.SessionPart
+	for _, msg := range messages {
+		if msg.OfUser == nil {
+			continue
+		}
+		if msg.OfUser.Content.OfString.Valid() {
+			parts = append(parts, models.SessionPart{Text: msg.OfUser.Content.OfString.Value})
+		}
+		for _, part := range msg.OfUser.Content.OfArrayOfContentParts {
+			switch {
+			case part.OfText != nil:
+				parts = append(parts, models.SessionPart{Text: part.OfText.Text})
+			case part.OfImageURL != nil:
+				parts = append(parts, models.SessionPart{ImageURL: part.OfImageURL.ImageURL.URL})
+			}
+		}
+	}
+	return parts
+}
+
+// trimHistory drops the oldest question and answer pairs until the rest fits into budget tokens
+func trimHistory(history []models.SessionMessage, budget int) []models.SessionMessage {
+	start := len(history)
+	for start >= 2 {
+		tokens := estimateTokens(history[start-2].Content) + estimateTokens(history[start-1].Content)
+		if tokens > budget {
+			break
+		}
+		budget -= tokens
+		start -= 2
+	}
+	return history[start:]
+}
+
+func estimateTokens(text string) int {
+	return len(text)/charsPerToken + 1
+}
+
+func (s *SessionService) sessionKey(id string) string {
+	return s.keyPrefix + id
+}
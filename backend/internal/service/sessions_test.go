@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/cache"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+// blockingQueue never admits a call, the first message keeps the session busy until its stream ends
+type blockingQueue struct {
+	acquired chan struct{}
+}
+
+func (q blockingQueue) Acquire(ctx context.Context, _ func(position int)) (func(), error) {
+	q.acquired <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSessionSendStreamWaitEndsWithRequest(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	queue := blockingQueue{acquired: make(chan struct{}, 1)}
+	explainer := &ExplainService{logger: logger, queue: queue}
+	sessions := NewSessionService(logger, explainer, cache.NewMemoryStore(1<<20, time.Hour), "test", config.SessionsConfig{
+		TTL:              time.Hour,
+		MaxHistoryTokens: 1000,
+	})
+	hub := NewStreamHub(config.StreamConfig{
+		HeartbeatInterval: time.Second,
+		ResumeGrace:       time.Minute,
+		Retention:         time.Minute,
+		MaxDuration:       time.Minute,
+	})
+
+	session := &models.Session{ID: "session", FileFormat: TXT, Parts: []models.SessionPart{{Text: "A -> B"}}}
+	if err := sessions.save(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same way the handler starts a streamed message
+	send := func(reqCtx context.Context) (<-chan models.StreamEvent, error) {
+		return hub.Start(reqCtx, func(ctx context.Context) (<-chan models.StreamChunk, error) {
+			return sessions.SendStream(ctx, reqCtx, session.ID, &models.SessionMessageRequest{Content: "what is A?"})
+		})
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+	if _, err := send(firstCtx); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	<-queue.acquired
+
+	secondCtx, disconnect := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := send(secondCtx)
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		t.Fatalf("second message didn't wait for the first one: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	disconnect()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("second message error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second message keeps waiting after the client disconnected")
+	}
+
+	sessions.mu.Lock()
+	refs := sessions.locks[session.ID].refs
+	sessions.mu.Unlock()
+	if refs != 1 {
+		t.Fatalf("lock refs = %d, want 1 held by the first message", refs)
+	}
+}
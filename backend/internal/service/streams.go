@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+var ErrStreamNotFound = errors.New("stream not found or expired")
+
+// StreamHub runs streams detached from the client connection and buffers their events,
+// so a client reconnecting with Last-Event-ID gets the missed events and continues.
+// Buffers are kept in memory, as the generation itself runs in this process
+type StreamHub struct {
+	heartbeat   time.Duration
+	grace       time.Duration
+	retention   time.Duration
+	maxDuration time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*bufferedStream
+}
+
+type bufferedStream struct {
+	id     string
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	events      []models.StreamEvent
+	finished    bool
+	updated     chan struct{}
+	subscribers int
+	idle        *time.Timer
+}
+
+func NewStreamHub(cfg config.StreamConfig) *StreamHub {
+	return &StreamHub{
+		heartbeat:   cfg.HeartbeatInterval,
+		grace:       cfg.ResumeGrace,
+		retention:   cfg.Retention,
+		maxDuration: cfg.MaxDuration,
+		streams:     make(map[string]*bufferedStream),
+	}
+}
+
+// Heartbeat is the keep-alive interval for idle stream connections
+func (h *StreamHub) Heartbeat() time.Duration {
+	return h.heartbeat
+}
+
+// Start runs start with a context which outlives the request for the resume grace period
+// and subscribes the caller from the first event
+func (h *StreamHub) Start(
+	ctx context.Context,
+	start func(ctx context.Context) (<-chan models.StreamChunk, error),
+) (<-chan models.StreamEvent, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	streamCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.maxDuration)
+	chunks, err := start(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	stream := &bufferedStream{
+		id:      id,
+		cancel:  cancel,
+		updated: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.streams[id] = stream
+	h.mu.Unlock()
+
+	go h.run(stream, chunks)
+	return h.subscribe(ctx, stream, 0), nil
+}
+
+// Resume subscribes to the stream of lastEventID starting from the next event
+func (h *StreamHub) Resume(ctx context.Context, lastEventID string) (<-chan models.StreamEvent, error) {
+	id, seq, ok := parseEventID(lastEventID)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid event id %q", ErrStreamNotFound, lastEventID)
+	}
+
+	h.mu.Lock()
+	stream, found := h.streams[id]
+	h.mu.Unlock()
+	if !found {
+		return nil, ErrStreamNotFound
+	}
+	return h.subscribe(ctx, stream, seq+1), nil
+}
+
+func (h *StreamHub) run(stream *bufferedStream, chunks <-chan models.StreamChunk) {
+	for chunk := range chunks {
+		stream.mu.Lock()
+		stream.events = append(stream.events, models.StreamEvent{
+			ID:    eventID(stream.id, len(stream.events)),
+			Chunk: chunk,
+		})
+		stream.notify()
+		stream.mu.Unlock()
+	}
+
+	stream.mu.Lock()
+	stream.finished = true
+	if stream.idle != nil {
+		stream.idle.Stop()
+	}
+	stream.notify()
+	stream.mu.Unlock()
+	stream.cancel()
+
+	time.AfterFunc(h.retention, func() {
+		h.mu.Lock()
+		delete(h.streams, stream.id)
+		h.mu.Unlock()
+	})
+}
+
+// subscribe replays events from seq and follows the stream until it finishes or ctx is done
+func (h *StreamHub) subscribe(ctx context.Context, stream *bufferedStream, seq int) <-chan models.StreamEvent {
+	stream.join()
+
+	ch := make(chan models.StreamEvent)
+	go func() {
+		defer close(ch)
+		defer stream.leave(h.grace)
+
+		for {
+			stream.mu.Lock()
+			for seq >= len(stream.events) && !stream.finished {
+				updated := stream.updated
+				stream.mu.Unlock()
+				select {
+				case <-updated:
+				case <-ctx.Done():
+					return
+				}
+				stream.mu.Lock()
+			}
+			if seq >= len(stream.events) {
+				stream.mu.Unlock()
+				return
+			}
+			event := stream.events[seq]
+			stream.mu.Unlock()
+
+			select {
+			case ch <- event:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// notify wakes up subscribers waiting for events, must be called with mu held
+func (s *bufferedStream) notify() {
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+func (s *bufferedStream) join() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers++
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+}
+
+// leave cancels the generation when nobody reconnects within grace
+func (s *bufferedStream) leave(grace time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers--
+	if s.subscribers == 0 && !s.finished {
+		s.idle = time.AfterFunc(grace, s.cancel)
+	}
+}
+
+func eventID(streamID string, seq int) string {
+	return fmt.Sprintf("%s:%d", streamID, seq)
+}
+
+func parseEventID(eventID string) (string, int, bool) {
+	id, rawSeq, ok := strings.Cut(eventID, ":")
+	if !ok || id == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(rawSeq)
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return id, seq, true
+}
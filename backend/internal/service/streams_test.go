@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+)
+
+func newTestStreamHub(grace time.Duration) *StreamHub {
+	return NewStreamHub(config.StreamConfig{ResumeGrace: grace, Retention: time.Minute, MaxDuration: time.Minute})
+}
+
+// startTestStream starts a stream fed by the returned channel, streamCtx is the generation context
+func startTestStream(t *testing.T, h *StreamHub, ctx context.Context) (<-chan models.StreamEvent, chan<- models.StreamChunk, <-chan context.Context) {
+	t.Helper()
+	chunks := make(chan models.StreamChunk)
+	streamCtx := make(chan context.Context, 1)
+	events, err := h.Start(ctx, func(ctx context.Context) (<-chan models.StreamChunk, error) {
+		streamCtx <- ctx
+		return chunks, nil
+	})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return events, chunks, streamCtx
+}
+
+func nextEvent(t *testing.T, events <-chan models.StreamEvent) models.StreamEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return models.StreamEvent{}
+}
+
+func TestStreamResume(t *testing.T) {
+	h := newTestStreamHub(time.Minute)
+	ctx, disconnect := context.WithCancel(context.Background())
+	events, chunks, _ := startTestStream(t, h, ctx)
+
+	chunks <- models.StreamChunk{Delta: "A "}
+	first := nextEvent(t, events)
+	chunks <- models.StreamChunk{Delta: "calls "}
+	disconnect()
+
+	// generated while the client was away
+	chunks <- models.StreamChunk{Delta: "B"}
+	chunks <- models.StreamChunk{Done: true}
+	close(chunks)
+
+	resumed, err := h.Resume(context.Background(), first.ID)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	var explanation string
+	for event := range resumed {
+		explanation += event.Chunk.Delta
+	}
+	if explanation != "calls B" {
+		t.Errorf("resumed explanation = %q, want %q", explanation, "calls B")
+	}
+
+	// a second client can replay the whole finished stream
+	id, _, _ := parseEventID(first.ID)
+	replayed, err := h.Resume(context.Background(), eventID(id, 0))
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	var ids []string
+	for event := range replayed {
+		ids = append(ids, event.ID)
+	}
+	if len(ids) != 3 || ids[0] != eventID(id, 1) || ids[2] != eventID(id, 3) {
+		t.Errorf("replayed ids = %v", ids)
+	}
+}
+
+func TestStreamResumeUnknown(t *testing.T) {
+	h := newTestStreamHub(time.Minute)
+	for _, lastEventID := range []string{"", "abc", "abc:x", "abc:-1", ":1", "abc:1"} {
+		if _, err := h.Resume(context.Background(), lastEventID); !errors.Is(err, ErrStreamNotFound) {
+			t.Errorf("Resume(%q) error = %v, want ErrStreamNotFound", lastEventID, err)
+		}
+	}
+}
+
+func TestStreamCancelledAfterGrace(t *testing.T) {
+	const grace = 50 * time.Millisecond
+	h := newTestStreamHub(grace)
+	ctx, disconnect := context.WithCancel(context.Background())
+	events, chunks, streamCtx := startTestStream(t, h, ctx)
+	generation := <-streamCtx
+
+	chunks <- models.StreamChunk{Delta: "A"}
+	first := nextEvent(t, events)
+	disconnect()
+
+	// reconnecting within grace keeps the generation running
+	time.Sleep(grace / 2)
+	resumeCtx, leave := context.WithCancel(context.Background())
+	resumed, err := h.Resume(resumeCtx, first.ID)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	time.Sleep(grace)
+	if generation.Err() != nil {
+		t.Fatal("generation cancelled while a client is subscribed")
+	}
+
+	leave()
+	for range resumed {
+	}
+	select {
+	case <-generation.Done():
+	case <-time.After(time.Second):
+		t.Fatal("generation kept running without clients")
+	}
+}
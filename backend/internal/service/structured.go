@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+const structuredAttempts = 3
+
+var structuredSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"diagram_type": map[string]any{"type": "string"},
+		"title":        map[string]any{"type": "string"},
+		"components": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":        map[string]any{"type": "string"},
+					"type":        map[string]any{"type": "string"},
+					"description": map[string]any{"type": "string"},
+				},
+				"required":             []string{"name", "type", "description"},
+				"additionalProperties": false,
+			},
+		},
+		"relationships": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"source": map[string]any{"type": "string"},
+					"target": map[string]any{"type": "string"},
+					"direction": map[string]any{
+						"type": "string",
+						"enum": []string{
+							models.DirectionForward,
+							models.DirectionBackward,
+							models.DirectionBidirectional,
+							models.DirectionNone,
+						},
+					},
+					"label": map[string]any{"type": "string"},
+				},
+				"required":             []string{"source", "target", "direction", "label"},
+				"additionalProperties": false,
+			},
+		},
+		"issues": map[string]any{
+			"type":  "array",
+			"items": map[string]any{"type": "string"},
+		},
+		"summary": map[string]any{"type": "string"},
+	},
+	"required":             []string{"diagram_type", "title", "components", "relationships", "issues", "summary"},
+	"additionalProperties": false,
+}
+
+// sendStructured asks the model for a JSON answer constrained by the schema and
+// re-asks with the validation error when the answer can't be parsed or is incomplete
+func (e *ExplainService) sendStructured(ctx context.Context, params *openai.ChatCompletionNewParams, format string) (*models.ExplainResponse, error) {
+	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   "diagram_explanation",
+				Strict: openai.Bool(true),
+				Schema: structuredSchema,
+			},
+		},
+	}
+
+	// usage and latency cover all attempts
+	var (
+		stats   *completionStats
+		lastErr error
+	)
+	for attempt := 1; attempt <= structuredAttempts; attempt++ {
+		resp, callStats, err := e.complete(ctx, params, format)
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI client error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("OpenAI client error: empty choices")
+		}
+		if stats == nil {
+			stats = callStats
+		} else {
+			stats.add(callStats)
+		}
+
+		content := resp.Choices[0].Message.Content
+		structured, err := parseStructured(content)
+		if err == nil {
+			return &models.ExplainResponse{
+				Explanation: structured.Summary,
+				Structured:  structured,
+				Model:       stats.model(),
+				Latency:     stats.finish(),
+				Usage:       stats.usage,
+			}, nil
+		}
+
+		lastErr = err
+		e.logger.Printf("invalid structured answer, attempt %d/%d: %v\n", attempt, structuredAttempts, err)
+		params.Messages = append(params.Messages,
+			openai.AssistantMessage(content),
+			openai.UserMessage(fmt.Sprintf(structuredRetryPrompt, err)),
+		)
+	}
+
+	return nil, fmt.Errorf("model returned invalid structured answer: %w", lastErr)
+}
+
+// sendStructuredStream emits the whole structured answer as one chunk,
+// partial JSON is useless for clients
+func (e *ExplainService) sendStructuredStream(ctx context.Context, req *models.ExplainRequest, formatInfo models.FormatInfo) <-chan models.StreamChunk {
+	ch := make(chan models.StreamChunk, 2)
+	ch <- stageChunk(models.StageQueued)
+
+	go func() {
+		defer close(ch)
+
+		resp, err := e.sendResolved(ctx, req, formatInfo)
+		if err != nil {
+			sendOrStop(ctx, ch, models.StreamChunk{Err: err})
+			return
+		}
+
+		if !sendOrStop(ctx, ch, models.StreamChunk{Meta: &models.StreamMeta{FormatInfo: resp.FormatInfo, Cached: resp.Cached, Similarity: resp.Similarity}}) {
+			return
+		}
+		if resp.Latency != nil {
+			usage := &models.StreamStage{Name: models.StageUsage, Model: resp.Model, Usage: resp.Usage, Latency: resp.Latency}
+			if !sendOrStop(ctx, ch, models.StreamChunk{Stage: usage}) {
+				return
+			}
+		}
+		sendOrStop(ctx, ch, models.StreamChunk{Delta: resp.Explanation, Structured: resp.Structured, Done: true})
+	}()
+
+	return ch
+}
+
+func parseStructured(content string) (*models.StructuredExplanation, error) {
+	// some models wrap JSON into a markdown code block even with response_format
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	var structured models.StructuredExplanation
+	if err := sonic.UnmarshalString(strings.TrimSpace(content), &structured); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := structured.Validate(); err != nil {
+		return nil, err
+	}
+	return &structured, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/openai/openai-go/v3"
+)
+
+const validStructured = `{"diagram_type":"flowchart","title":"Orders","components":[{"name":"API","type":"service","description":"serves orders"}],` +
+	`"relationships":[{"source":"API","target":"DB","direction":"forward","label":"SQL"}],"issues":[],"summary":"API stores orders in DB"}`
+
+func TestParseStructured(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		wantErr string
+	}{
+		"plain":             {content: validStructured},
+		"markdown block":    {content: "```json\n" + validStructured + "\n```"},
+		"bare block":        {content: "```\n" + validStructured + "\n```"},
+		"not json":          {content: "The diagram shows orders", wantErr: "invalid JSON"},
+		"no summary":        {content: `{"diagram_type":"flowchart"}`, wantErr: "summary is empty"},
+		"unnamed component": {content: `{"diagram_type":"a","summary":"b","components":[{"type":"service"}]}`, wantErr: "components[0].name"},
+		"unknown direction": {
+			content: `{"diagram_type":"a","summary":"b","relationships":[{"source":"A","target":"B","direction":"up"}]}`,
+			wantErr: `direction "up"`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			structured, err := parseStructured(tc.content)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStructured: %v", err)
+			}
+			if structured.Summary != "API stores orders in DB" || len(structured.Relationships) != 1 {
+				t.Fatalf("structured = %+v", structured)
+			}
+		})
+	}
+}
+
+func TestSendStructuredRetries(t *testing.T) {
+	e := newTestExplainer(t, slowQueue{}, "not json", `{"diagram_type":"flowchart"}`, validStructured)
+
+	params := &openai.ChatCompletionNewParams{Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("explain")}}
+	resp, err := e.sendStructured(context.Background(), params, TXT)
+	if err != nil {
+		t.Fatalf("sendStructured: %v", err)
+	}
+	if resp.Structured == nil || resp.Explanation != resp.Structured.Summary {
+		t.Fatalf("response = %+v", resp)
+	}
+	// each failed attempt adds the answer and the validation error
+	if len(params.Messages) != 5 {
+		t.Errorf("messages = %d, want 5", len(params.Messages))
+	}
+	if retry := params.Messages[4].OfUser; retry == nil || !strings.Contains(retry.Content.OfString.Value, "summary is empty") {
+		t.Errorf("retry prompt doesn't carry the validation error: %+v", params.Messages[4])
+	}
+	if want := (models.Usage{PromptTokens: 30, CompletionTokens: 15, TotalTokens: 45}); resp.Usage == nil || *resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v summed over the attempts", resp.Usage, want)
+	}
+}
+
+func TestSendStructuredGivesUp(t *testing.T) {
+	e := newTestExplainer(t, slowQueue{}, "not json")
+
+	params := &openai.ChatCompletionNewParams{Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("explain")}}
+	_, err := e.sendStructured(context.Background(), params, TXT)
+	if err == nil || !strings.Contains(err.Error(), "invalid structured answer") {
+		t.Fatalf("error = %v, want invalid structured answer", err)
+	}
+	if want := 1 + 2*structuredAttempts; len(params.Messages) != want {
+		t.Errorf("messages = %d, want %d after %d attempts", len(params.Messages), want, structuredAttempts)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/metrics"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/openai/openai-go/v3"
+)
+
+// completionStats measures a model call, firstToken is set by streams only.
+// The model is read from params since the pool picks it after stats are created.
+// elapsed is set once a non-streamed call is stopped, streams are measured until finish
+type completionStats struct {
+	format     string
+	params     *openai.ChatCompletionNewParams
+	start      time.Time
+	firstToken time.Time
+	elapsed    time.Duration
+	stopped    bool
+	usage      *models.Usage
+}
+
+func newCompletionStats(format string, params *openai.ChatCompletionNewParams) *completionStats {
+	return &completionStats{
+		format: format,
+		params: params,
+		start:  time.Now(),
+	}
+}
+
+func (s *completionStats) model() string {
+	return string(s.params.Model)
+}
+
+func (s *completionStats) token() {
+	if s.firstToken.IsZero() {
+		s.firstToken = time.Now()
+		metrics.LLMTimeToFirstToken(s.format, s.model(), s.firstToken.Sub(s.start))
+	}
+}
+
+// addUsage sums usage of several calls, backends without usage reporting send zeros
+func (s *completionStats) addUsage(usage openai.CompletionUsage) {
+	if usage.TotalTokens == 0 {
+		return
+	}
+	if s.usage == nil {
+		s.usage = &models.Usage{}
+	}
+	s.usage.PromptTokens += usage.PromptTokens
+	s.usage.CompletionTokens += usage.CompletionTokens
+	s.usage.TotalTokens += usage.TotalTokens
+}
+
+// stop ends the measurement of a non-streamed call
+func (s *completionStats) stop() {
+	s.elapsed = time.Since(s.start)
+	s.stopped = true
+}
+
+// add counts another stopped call of the same request, e.g. a retry
+func (s *completionStats) add(other *completionStats) {
+	s.elapsed += other.elapsed
+	if other.usage == nil {
+		return
+	}
+	s.addUsage(openai.CompletionUsage{
+		PromptTokens:     other.usage.PromptTokens,
+		CompletionTokens: other.usage.CompletionTokens,
+		TotalTokens:      other.usage.TotalTokens,
+	})
+}
+
+// finish reports metrics of the call and returns its latency
+func (s *completionStats) finish() *models.Latency {
+	total := time.Since(s.start)
+	if s.stopped {
+		total = s.elapsed
+	}
+	latency := &models.Latency{TotalMs: total.Milliseconds()}
+
+	// speed of a stream is measured without the prompt processing time
+	generation := total
+	if !s.firstToken.IsZero() {
+		ttft := s.firstToken.Sub(s.start)
+		latency.TimeToFirstTokenMs = ttft.Milliseconds()
+		generation -= ttft
+	}
+
+	if s.usage != nil {
+		metrics.LLMTokens("prompt", s.format, s.model(), s.usage.PromptTokens)
+		metrics.LLMTokens("completion", s.format, s.model(), s.usage.CompletionTokens)
+
+		if generation > 0 && s.usage.CompletionTokens > 0 {
+			latency.TokensPerSecond = float64(s.usage.CompletionTokens) / generation.Seconds()
+			metrics.LLMTokensPerSecond(s.format, s.model(), latency.TokensPerSecond)
+		}
+	}
+	return latency
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/config"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/llm"
+	"github.com/kdduha/itmo-megaschool-2026/backend/internal/models"
+	"github.com/openai/openai-go/v3"
+)
+
+// slowQueue admits every call after delay
+type slowQueue struct {
+	delay time.Duration
+}
+
+func (q slowQueue) Acquire(ctx context.Context, _ func(position int)) (func(), error) {
+	select {
+	case <-time.After(q.delay):
+		return func() {}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// newTestExplainer serves the answers one per chat completion call, the last one is repeated
+func newTestExplainer(t *testing.T, queue modelQueue, answers ...string) *ExplainService {
+	t.Helper()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answer := answers[min(int(calls.Add(1))-1, len(answers)-1)]
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"c","object":"chat.completion","created":0,"model":"test",`+
+			`"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%q}}],`+
+			`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`, answer)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config.OpenAIConfig{BaseURL: srv.URL, Model: "test", CallTimeout: time.Minute, MaxFailures: 3}
+	pool, err := llm.NewPool(log.New(io.Discard, "", 0), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewExplainService(log.New(io.Discard, "", 0), pool, queue, cfg, config.UploadConfig{MaxSize: 1 << 20}, config.QueueConfig{})
+}
+
+func TestCompleteStatsSkipQueueWait(t *testing.T) {
+	const wait = 200 * time.Millisecond
+	e := newTestExplainer(t, slowQueue{delay: wait}, "answer")
+
+	params := &openai.ChatCompletionNewParams{Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("explain")}}
+	start := time.Now()
+	_, stats, err := e.complete(context.Background(), params, TXT)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if time.Since(start) < wait {
+		t.Fatal("the call didn't wait in the queue")
+	}
+
+	latency := stats.finish()
+	if latency.TotalMs >= wait.Milliseconds() {
+		t.Errorf("TotalMs = %d includes the queue wait of %s", latency.TotalMs, wait)
+	}
+	if want := (models.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}); stats.usage == nil || *stats.usage != want {
+		t.Errorf("usage = %+v, want %+v", stats.usage, want)
+	}
+	if stats.model() != "test" {
+		t.Errorf("model = %q, want %q", stats.model(), "test")
+	}
+}
+
+func TestCompletionStatsAdd(t *testing.T) {
+	first := &completionStats{elapsed: time.Second, stopped: true, usage: &models.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}
+	retry := &completionStats{elapsed: 2 * time.Second, stopped: true, usage: &models.Usage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30}}
+	first.add(retry)
+
+	if want := (models.Usage{PromptTokens: 30, CompletionTokens: 15, TotalTokens: 45}); *first.usage != want {
+		t.Errorf("usage = %+v, want %+v", *first.usage, want)
+	}
+	first.params = &openai.ChatCompletionNewParams{}
+	latency := first.finish()
+	if latency.TotalMs != 3000 {
+		t.Errorf("TotalMs = %d, want 3000", latency.TotalMs)
+	}
+	if latency.TokensPerSecond != 5 {
+		t.Errorf("TokensPerSecond = %v, want 5", latency.TokensPerSecond)
+	}
+}